@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"go-crud/internal/config"
 	"go-crud/internal/handlers"
+	"go-crud/internal/repositories"
+	"go-crud/internal/services"
 	"go-crud/internal/utils"
+	"go-crud/middleware"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func main() {
@@ -27,12 +35,43 @@ func main() {
 	dbPort := os.Getenv("DB_PORT")
 	dbName := os.Getenv("DB_NAME")
 
-	//Load JWT secret key
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("JWT_SECRET environment variable not set")
+	// Load JWT keys: an RS256/EdDSA private key if configured, the HS256 secret otherwise
+	var keySet *utils.KeySet
+	if keyFile := os.Getenv("JWT_SIGNING_KEY_FILE"); keyFile != "" {
+		keySet, err = loadJWTKeySet(keyFile, os.Getenv("JWT_SIGNING_KEY_ID"), os.Getenv("JWT_VERIFICATION_KEYS"))
+		if err != nil {
+			log.Fatalf("Error loading JWT keys: %v", err)
+		}
+	} else {
+		jwtSecret := os.Getenv("JWT_SECRET")
+		if jwtSecret == "" {
+			log.Fatal("JWT_SECRET environment variable not set")
+		}
+		keySet = utils.NewHMACKeySet([]byte(jwtSecret))
+	}
+
+	tokens := utils.NewTokenService(keySet, envOrDefault("JWT_ISSUER", "go-crud"), envOrDefault("JWT_AUDIENCE", "go-crud"))
+	tokens.AccessTokenTTL = durationFromEnv("JWT_ACCESS_TOKEN_TTL", tokens.AccessTokenTTL)
+	tokens.ClockSkew = durationFromEnv("JWT_CLOCK_SKEW", tokens.ClockSkew)
+	tokens.ImpersonationTTL = durationFromEnv("JWT_IMPERSONATION_TTL", tokens.ImpersonationTTL)
+
+	// New passwords are hashed with Argon2id unless bcrypt is configured; both are always verified
+	switch hasher := envOrDefault("PASSWORD_HASHER", "argon2id"); hasher {
+	case "argon2id":
+		argon := utils.NewArgon2idHasher()
+		argon.Memory = uint32(intFromEnv("ARGON2_MEMORY_KIB", int(argon.Memory)))
+		argon.Iterations = uint32(intFromEnv("ARGON2_ITERATIONS", int(argon.Iterations)))
+		parallelism := intFromEnv("ARGON2_PARALLELISM", int(argon.Parallelism))
+		if parallelism > 255 {
+			log.Fatal("ARGON2_PARALLELISM must be at most 255")
+		}
+		argon.Parallelism = uint8(parallelism)
+		utils.Passwords = utils.NewPasswordHashing(argon)
+	case "bcrypt":
+		utils.Passwords = utils.NewPasswordHashing(utils.NewBcryptHasher(intFromEnv("BCRYPT_COST", bcrypt.DefaultCost)))
+	default:
+		log.Fatalf("Unsupported PASSWORD_HASHER %q", hasher)
 	}
-	utils.SetJWTSecret(jwtSecret)
 
 	// Construct connection string
 	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
@@ -46,12 +85,201 @@ func main() {
 	// Initialize router
 	router := mux.NewRouter()
 
+	// Shared token services, so that revocations are visible to every route immediately
+	revocations := services.NewRevocationService(repositories.NewRevocationRepository(db))
+	authService := services.NewAuthService(repositories.NewRefreshTokenRepository(db), repositories.NewSessionRepository(db),
+		repositories.NewUserRepository(db), revocations, tokens)
+	apiKeys := services.NewAPIKeyService(repositories.NewAPIKeyRepository(db), repositories.NewUserRepository(db))
+	authMiddleware := middleware.AuthMiddleware(tokens, revocations, apiKeys)
+
+	// Password policy, optionally with an offline list of breached passwords
+	policy := services.DefaultPasswordPolicy()
+	policy.MinLength = intFromEnv("PASSWORD_MIN_LENGTH", policy.MinLength)
+	policy.MaxLength = intFromEnv("PASSWORD_MAX_LENGTH", policy.MaxLength)
+	policy.MinCharacterClasses = intFromEnv("PASSWORD_MIN_CHARACTER_CLASSES", policy.MinCharacterClasses)
+	policy.HistorySize = intFromEnv("PASSWORD_HISTORY_SIZE", policy.HistorySize)
+	policy.DisallowPersonalInfo = os.Getenv("PASSWORD_ALLOW_PERSONAL_INFO") != "true"
+	var breached services.BreachedPasswordChecker
+	if path := os.Getenv("PASSWORD_BREACH_LIST"); path != "" {
+		filter, err := utils.LoadBreachedPasswordFilter(path, 0.001)
+		if err != nil {
+			log.Fatalf("Error loading breached password list: %v", err)
+		}
+		breached = filter
+	}
+	passwords := services.NewPasswordPolicyService(policy, repositories.NewPasswordHistoryRepository(db), breached)
+
+	// Outgoing email is logged until a real mailer is configured
+	mailer := services.NewLogMailer()
+	passwordResets := services.NewPasswordResetService(repositories.NewActionTokenRepository(db),
+		repositories.NewUserRepository(db), mailer, authService, passwords, os.Getenv("PASSWORD_RESET_URL"))
+	emailVerifications := services.NewEmailVerificationService(repositories.NewActionTokenRepository(db),
+		repositories.NewUserRepository(db), mailer, os.Getenv("EMAIL_VERIFICATION_URL"),
+		os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true")
+	magicLinks := services.NewMagicLinkService(repositories.NewActionTokenRepository(db),
+		repositories.NewUserRepository(db), mailer, os.Getenv("MAGIC_LINK_URL"))
+
+	mfa := services.NewMFAService(repositories.NewMFARepository(db), envOrDefault("MFA_ISSUER", "go-crud"))
+
+	// Failed logins are counted in memory unless several instances must share them
+	var loginAttempts repositories.LoginAttemptRepositoryInterface = repositories.NewInMemoryLoginAttemptRepository()
+	if os.Getenv("LOGIN_THROTTLE_STORE") == "postgres" {
+		loginAttempts = repositories.NewLoginAttemptRepository(db)
+	}
+	loginThrottle := services.NewLoginThrottleService(loginAttempts)
+
+	oauthRepo := repositories.NewOAuthRepository(db)
+	authService.OAuth = oauthRepo
+	oauth := services.NewOAuthService(oauthRepo, repositories.NewUserRepository(db), tokens, revocations)
+
+	// External identity providers, e.g. OIDC_PROVIDERS=google with OIDC_GOOGLE_ISSUER etc.
+	oidc := services.NewOIDCService(oidcProvidersFromEnv(os.Getenv("OIDC_PROVIDERS")),
+		repositories.NewOIDCRepository(db), repositories.NewUserRepository(db))
+
+	// Only the listed support staff can impersonate users, e.g. SUPPORT_USER_IDS=3,17
+	impersonation := services.NewImpersonationService(repositories.NewImpersonationRepository(db),
+		repositories.NewUserRepository(db), tokens, idsFromEnv("SUPPORT_USER_IDS"))
+
+	// Deleted users are purged for good after the retention period, e.g. USER_RETENTION_PERIOD=720h
+	userPurge := services.NewUserPurgeService(repositories.NewUserRepository(db),
+		durationFromEnv("USER_RETENTION_PERIOD", services.DefaultUserRetention))
+	userPurge.Interval = durationFromEnv("USER_PURGE_INTERVAL", userPurge.Interval)
+	if userPurge.Retention <= 0 || userPurge.Interval <= 0 {
+		log.Fatal("USER_RETENTION_PERIOD and USER_PURGE_INTERVAL must be positive")
+	}
+	go userPurge.Run(context.Background())
+
+	// Bulk imports hash passwords on USER_IMPORT_WORKERS goroutines and insert
+	// USER_IMPORT_BATCH_SIZE users per transaction
+	userImports := services.NewUserImportService(repositories.NewUserRepository(db), passwords)
+	userImports.Workers = intFromEnv("USER_IMPORT_WORKERS", userImports.Workers)
+	userImports.BatchSize = intFromEnv("USER_IMPORT_BATCH_SIZE", userImports.BatchSize)
+
 	// Register routes
-	handlers.RegisterUserRoutes(router, db, []byte(jwtSecret))
+	handlers.RegisterUserRoutes(router, db, authService, mfa, loginThrottle, apiKeys, passwords, emailVerifications,
+		userImports, os.Getenv("REQUIRE_IF_MATCH") == "true", authMiddleware)
+
+	handlers.RegisterAuthRoutes(router, db, authService, passwordResets, emailVerifications, mfa, loginThrottle, tokens, oidc, magicLinks, passwords, authMiddleware)
 
-	handlers.RegisterAuthRoutes(router, db)
+	handlers.RegisterOAuthRoutes(router, oauth, authMiddleware)
+
+	handlers.RegisterImpersonationRoutes(router, impersonation, authMiddleware)
 
 	// Start the server
 	log.Println("Server is running on port 8080")
 	log.Fatal(http.ListenAndServe(":8080", router))
 }
+
+// loadJWTKeySet builds the asymmetric key set from a PEM private key and a
+// comma-separated list of retired public keys ("kid=path" or just "path"),
+// which stay valid for verification after a key rotation.
+func loadJWTKeySet(signingKeyFile, signingKeyID, verificationKeys string) (*utils.KeySet, error) {
+	signingKey, err := utils.LoadSigningKeyFile(signingKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	keySet, err := utils.NewKeySet(signingKey, signingKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range strings.Split(verificationKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, found := strings.Cut(entry, "=")
+		if !found {
+			kid, path = "", entry
+		}
+		publicKey, err := utils.LoadVerificationKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := keySet.AddVerificationKey(kid, publicKey); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return keySet, nil
+}
+
+// oidcProvidersFromEnv configures the comma-separated list of OIDC providers
+// from OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL and
+// _AUTO_CREATE.
+func oidcProvidersFromEnv(names string) []*services.OIDCProvider {
+	var providers []*services.OIDCProvider
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := services.OIDCProviderConfig{
+			Name:            name,
+			Issuer:          os.Getenv(prefix + "ISSUER"),
+			ClientID:        os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret:    os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:     os.Getenv(prefix + "REDIRECT_URL"),
+			AutoCreateUsers: os.Getenv(prefix+"AUTO_CREATE") == "true",
+		}
+		if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+			log.Fatalf("%sISSUER, %sCLIENT_ID and %sREDIRECT_URL must be set", prefix, prefix, prefix)
+		}
+		providers = append(providers, services.NewOIDCProvider(config))
+	}
+	return providers
+}
+
+// envOrDefault returns the environment variable, or fallback when it is unset.
+func envOrDefault(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// intFromEnv parses the environment variable as a positive integer, or returns
+// fallback when it is unset.
+func intFromEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Fatalf("Invalid %s: %q", name, value)
+	}
+	return n
+}
+
+// idsFromEnv parses the environment variable as a comma-separated list of
+// positive IDs.
+func idsFromEnv(name string) []int {
+	var ids []int
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			log.Fatalf("Invalid %s: %q", name, value)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// durationFromEnv parses the environment variable as a duration such as "15m",
+// or returns fallback when it is unset.
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return d
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// ListAPIKeys returns the API keys of a user. Keys themselves are never returned.
+func (h *UserHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := h.APIKeys.ListKeys(userID)
+	if err != nil {
+		http.Error(w, "Error fetching API keys", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(keys)
+}
+
+// CreateAPIKey creates an API key for a user. The plaintext key is only
+// included in this response.
+func (h *UserHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := models.Validate.Struct(req); err != nil {
+		http.Error(w, validationMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.Service.GetUserByID(userID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	key, plaintext, err := h.APIKeys.CreateKey(userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAPIKeyExpiry) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Error creating API key", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreateAPIKeyResponse{APIKey: key, Key: plaintext})
+}
+
+func (h *UserHandler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, keyID, ok := apiKeyRouteIDs(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := h.APIKeys.GetKey(userID, keyID)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(key)
+}
+
+// UpdateAPIKey renames an API key or replaces its scopes.
+func (h *UserHandler) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, keyID, ok := apiKeyRouteIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := models.Validate.Struct(req); err != nil {
+		http.Error(w, validationMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.APIKeys.UpdateKey(userID, keyID, req)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(key)
+}
+
+// DeleteAPIKey revokes an API key immediately.
+func (h *UserHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, keyID, ok := apiKeyRouteIDs(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.APIKeys.DeleteKey(userID, keyID); err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "API key deleted successfully"})
+}
+
+// apiKeyRouteIDs parses the user and key IDs from the route, writing a 400 on failure.
+func apiKeyRouteIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	params := mux.Vars(r)
+	userID, err := strconv.Atoi(params["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	keyID, err := strconv.Atoi(params["keyID"])
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return userID, keyID, true
+}
+
+// validationMessage lists the fields that failed validation, like Register does.
+func validationMessage(err error) string {
+	errorMessage := "Validation failed:"
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		for _, e := range validationErrors {
+			errorMessage += " " + e.Field() + " is invalid"
+		}
+	}
+	return errorMessage
+}
+
+func writeAPIKeyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, repositories.ErrAPIKeyNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, "Error processing API key", http.StatusInternalServerError)
+}
@@ -10,16 +10,41 @@ import (
 	"go-crud/internal/repositories"
 	"go-crud/internal/services"
 	"go-crud/internal/utils"
+	"go-crud/middleware"
+	"io"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type AuthHandler struct {
-	Service *services.UserService
+	Service            *services.UserService
+	AuthService        *services.AuthService
+	PasswordResets     *services.PasswordResetService
+	EmailVerifications *services.EmailVerificationService
+	MFA                *services.MFAService
+	LoginThrottle      *services.LoginThrottleService
+	Tokens             *utils.TokenService
+	OIDC               *services.OIDCService
+	MagicLinks         *services.MagicLinkService
 }
 
-func NewAuthHandler(service *services.UserService) *AuthHandler {
+func NewAuthHandler(service *services.UserService, authService *services.AuthService, passwordResets *services.PasswordResetService,
+	emailVerifications *services.EmailVerificationService, mfa *services.MFAService, loginThrottle *services.LoginThrottleService,
+	tokens *utils.TokenService, oidc *services.OIDCService, magicLinks *services.MagicLinkService) *AuthHandler {
 	return &AuthHandler{
-		Service: service,
+		Service:            service,
+		AuthService:        authService,
+		PasswordResets:     passwordResets,
+		EmailVerifications: emailVerifications,
+		MFA:                mfa,
+		LoginThrottle:      loginThrottle,
+		Tokens:             tokens,
+		OIDC:               oidc,
+		MagicLinks:         magicLinks,
 	}
 }
 
@@ -43,25 +68,33 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Hash the password before saving the user
-	hashedPassword, err := utils.HashPassword(user.PasswordHash)
+	// Self-registered accounts never get elevated roles and must verify their email
+	user.Role = models.RoleUser
+	user.EmailVerified = false
+
+	// Save the user to the database (the repository hashes the password)
+	newUser, err := h.Service.CreateUser(user)
 	if err != nil {
-		http.Error(w, "Error hashing password", http.StatusInternalServerError)
+		if writePasswordPolicyError(w, err) {
+			return
+		}
+		http.Error(w, "Error creating user", http.StatusInternalServerError)
 		return
 	}
-	user.PasswordHash = hashedPassword
 
-	// Save the user to the database
-	if _, err := h.Service.CreateUser(user); err != nil {
-		http.Error(w, "Error creating user", http.StatusInternalServerError)
-		return
+	// The account exists even if the email fails; the user can request a resend
+	if err := h.EmailVerifications.SendVerification(newUser); err != nil {
+		log.Printf("Error sending verification email: %v", err)
 	}
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "User registered successfully"})
 }
 
-// Login handles user login and generates a JWT token.
+// Login handles user login and issues an access token and a refresh token.
+// Users with two-factor authentication get an MFA challenge token instead,
+// to be exchanged at /login/mfa. Attempts are throttled per account and per
+// client IP before any password hashing happens.
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var credential struct {
 		Email    string `json:"email"`
@@ -72,9 +105,20 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ip := clientIP(r)
+	wait, err := h.LoginThrottle.Attempt(credential.Email, ip)
+	if err != nil {
+		http.Error(w, "Error checking login attempts", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		writeLoginThrottled(w, wait)
+		return
+	}
+
 	user, err := h.Service.GetUserByEmail(credential.Email)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, repositories.ErrUserNotFound) {
 			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 			return
 		}
@@ -86,22 +130,145 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
-	token, err := utils.GenerateToken(user.ID)
+	// The plain-text password is only available now, so outdated hashes are upgraded on login
+	if err := h.Service.RehashPassword(user.ID, user.PasswordHash, credential.Password); err != nil {
+		log.Printf("Error upgrading password hash: %v", err)
+	}
+	h.completeLogin(w, r, user)
+}
+
+// writeLoginThrottled rejects a login attempt until the wait is over.
+func writeLoginThrottled(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Too many failed login attempts", http.StatusTooManyRequests)
+}
+
+// completeLogin finishes a login once the user has been authenticated: it
+// issues an MFA challenge token if two-factor authentication is enabled, and
+// starts a session for the client with its token pair otherwise. Callers must
+// have reserved the attempt with LoginThrottle.Attempt.
+func (h *AuthHandler) completeLogin(w http.ResponseWriter, r *http.Request, user models.User) {
+	if err := h.EmailVerifications.CanLogin(user); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	mfaEnabled, err := h.MFA.IsEnabled(user.ID)
+	if err != nil {
+		http.Error(w, "Error fetching user", http.StatusInternalServerError)
+		return
+	}
+	if mfaEnabled {
+		challenge, err := h.Tokens.IssueMFAChallengeToken(user.ID)
+		if err != nil {
+			http.Error(w, "Error generating token", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(models.MFAChallengeResponse{MFARequired: true, MFAToken: challenge})
+		return
+	}
+
+	// The attempt only counts as a success once no further factor is needed
+	if err := h.LoginThrottle.RecordSuccess(user.Email, clientIP(r)); err != nil {
+		log.Printf("Error clearing login attempts: %v", err)
+	}
+	tokens, err := h.AuthService.IssueTokens(user, r.UserAgent(), clientIP(r))
 	if err != nil {
 		http.Error(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
-	json.NewEncoder(w).Encode(map[string]string{
-		"token": token,
-	})
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// clientIP returns the IP address of the client that sent the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Refresh rotates a refresh token and issues a new token pair.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.AuthService.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Error refreshing token", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout revokes the access token used for the request and ends its session.
+// Tokens from before sessions existed instead revoke the refresh token issued
+// alongside them, if supplied in the body.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	jti, expiresAt, ok := middleware.TokenIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	sessionID, _ := middleware.SessionIDFromContext(r.Context())
+
+	if err := h.AuthService.Logout(jti, expiresAt, userID, sessionID, req.RefreshToken); err != nil {
+		http.Error(w, "Error logging out", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
+}
+
+// JWKS publishes the public keys used to verify access tokens, so other
+// services can verify them without sharing a secret.
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.Tokens.Keys.JWKS())
 }
 
 // RegisterAuthRoutes registers authentication-related routes.
-func RegisterAuthRoutes(router *mux.Router, db *sql.DB) {
+func RegisterAuthRoutes(router *mux.Router, db *sql.DB, authService *services.AuthService,
+	passwordResets *services.PasswordResetService, emailVerifications *services.EmailVerificationService,
+	mfa *services.MFAService, loginThrottle *services.LoginThrottleService, tokens *utils.TokenService,
+	oidc *services.OIDCService, magicLinks *services.MagicLinkService, passwords *services.PasswordPolicyService, authMiddleware mux.MiddlewareFunc) {
 	repo := repositories.NewUserRepository(db)
-	service := services.NewUserService(repo)
-	handler := NewAuthHandler(service)
+	service := services.NewUserService(repo, passwords)
+	handler := NewAuthHandler(service, authService, passwordResets, emailVerifications, mfa, loginThrottle, tokens, oidc, magicLinks)
 
 	router.HandleFunc("/register", handler.Register).Methods("POST")
 	router.HandleFunc("/login", handler.Login).Methods("POST")
+	router.HandleFunc("/login/mfa", handler.LoginMFA).Methods("POST")
+	router.HandleFunc("/login/magic", handler.RequestMagicLink).Methods("POST")
+	router.HandleFunc("/login/magic/callback", handler.ConfirmMagicLink).Methods("GET")
+	router.HandleFunc("/login/magic/callback", handler.MagicLinkCallback).Methods("POST")
+	router.HandleFunc("/login/oidc/link", handler.LinkOIDCIdentity).Methods("POST")
+	router.HandleFunc("/login/oidc/{provider}", handler.BeginOIDCLogin).Methods("GET")
+	router.HandleFunc("/login/oidc/{provider}/callback", handler.OIDCCallback).Methods("GET")
+	router.HandleFunc("/token/refresh", handler.Refresh).Methods("POST")
+	router.Handle("/logout", authMiddleware(http.HandlerFunc(handler.Logout))).Methods("POST")
+	router.HandleFunc("/password/forgot", handler.ForgotPassword).Methods("POST")
+	router.HandleFunc("/password/reset", handler.ResetPassword).Methods("POST")
+	router.HandleFunc("/verify-email", handler.VerifyEmail).Methods("GET")
+	router.HandleFunc("/verify-email/resend", handler.ResendVerification).Methods("POST")
+	router.HandleFunc("/.well-known/jwks.json", handler.JWKS).Methods("GET")
 }
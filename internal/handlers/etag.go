@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/services"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// userETag returns the entity tag of a user representation, derived from the
+// user's version.
+func userETag(user models.User) string {
+	return `"` + strconv.Itoa(user.Version) + `"`
+}
+
+// ifMatchVersions parses the If-Match header into the user versions it
+// accepts. It returns nil, matching any version, when the header is absent or
+// "*"; tags that aren't user versions, and weak tags, match nothing.
+func ifMatchVersions(header string) []int {
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return nil
+	}
+	versions := []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+		if version, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// preconditions returns the versions accepted by the request's If-Match
+// header. When If-Match is required and missing, it responds with 428
+// Precondition Required and returns false.
+func (h *UserHandler) preconditions(w http.ResponseWriter, r *http.Request) ([]int, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" && h.RequireIfMatch {
+		http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+		return nil, false
+	}
+	return ifMatchVersions(header), true
+}
+
+// writeUserWithETag writes the user with its ETag, or 304 Not Modified if the
+// If-None-Match header already lists it.
+func writeUserWithETag(w http.ResponseWriter, r *http.Request, user models.User) {
+	etag := userETag(user)
+	w.Header().Set("ETag", etag)
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && etagListed(noneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}
+
+// etagListed reports whether an If-None-Match header lists the entity tag,
+// using the weak comparison.
+func etagListed(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeVersionConflict responds with 412 Precondition Failed to updates of a
+// user that was changed since the client read it, and with 409 Conflict to
+// updates without If-Match that kept losing races with other writes.
+func writeVersionConflict(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, repositories.ErrVersionConflict):
+		http.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, services.ErrConcurrentUpdate):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		return false
+	}
+	return true
+}
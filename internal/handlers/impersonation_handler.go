@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/services"
+	"go-crud/middleware"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type ImpersonationHandler struct {
+	Impersonation *services.ImpersonationService
+}
+
+func NewImpersonationHandler(impersonation *services.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{Impersonation: impersonation}
+}
+
+// RegisterImpersonationRoutes registers the routes to impersonate users and to
+// audit impersonation sessions.
+func RegisterImpersonationRoutes(router *mux.Router, impersonation *services.ImpersonationService, authMiddleware mux.MiddlewareFunc) {
+	handler := NewImpersonationHandler(impersonation)
+
+	// Impersonation tokens can't start another session; support staff are
+	// checked against the configured list rather than by role
+	firstParty := func(next http.Handler) http.Handler {
+		return authMiddleware(middleware.DenyScopedCredentials(middleware.DenyImpersonation(next)))
+	}
+	adminOnly := middleware.RequireRole(models.RoleAdmin)
+
+	router.Handle("/admin/impersonate/{id}", firstParty(http.HandlerFunc(handler.Impersonate))).Methods("POST")
+	router.Handle("/admin/impersonations", firstParty(adminOnly(http.HandlerFunc(handler.ListSessions)))).Methods("GET")
+}
+
+// Impersonate issues a short-lived token to act as the user. The reason is
+// required and recorded with the session.
+func (h *ImpersonationHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := models.Validate.Struct(req); err != nil {
+		http.Error(w, validationMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	response, err := h.Impersonation.Impersonate(actorID, userID, req.Reason, clientIP(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotSupportUser):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, services.ErrCannotImpersonate):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, repositories.ErrUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			log.Printf("Error starting impersonation: %v", err)
+			http.Error(w, "Error starting impersonation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ListSessions returns the impersonation audit log, optionally filtered by the
+// impersonated user with ?user_id=.
+func (h *ImpersonationHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	var userID *int
+	if value := r.URL.Query().Get("user_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		userID = &id
+	}
+
+	sessions, err := h.Impersonation.ListSessions(userID)
+	if err != nil {
+		http.Error(w, "Error fetching impersonation sessions", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(sessions)
+}
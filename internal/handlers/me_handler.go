@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/services"
+	"go-crud/middleware"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetMe returns the authenticated user.
+func (h *UserHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.Service.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeUserWithETag(w, r, user)
+}
+
+// UpdateMe partially updates the authenticated user's profile. Passwords are
+// changed through ChangePassword so the current password is always verified,
+// and the email can't be changed while impersonating.
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var updateUserReq models.UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&updateUserReq); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if updateUserReq.PasswordHash != nil {
+		http.Error(w, "Use PUT /me/password to change the password", http.StatusBadRequest)
+		return
+	}
+	if _, impersonating := middleware.ActorIDFromContext(r.Context()); impersonating && updateUserReq.Email != nil {
+		http.Error(w, errEmailChangeWhileImpersonating.Error(), http.StatusForbidden)
+		return
+	}
+
+	if err := validatePartialUpdate(updateUserReq); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ifMatch, ok := h.preconditions(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.Service.UpdateUser(userID, updateUserReq, ifMatch)
+	if err != nil {
+		if writeVersionConflict(w, err) {
+			return
+		}
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error updating user", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", userETag(user))
+	json.NewEncoder(w).Encode(map[string]string{"message": "User updated successfully"})
+}
+
+// DeleteMe deletes the authenticated user's account and signs them out everywhere.
+func (h *UserHandler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	ifMatch, ok := h.preconditions(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteUser(userID, ifMatch); err != nil {
+		if writeVersionConflict(w, err) {
+			return
+		}
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error deleting user", http.StatusInternalServerError)
+		return
+	}
+	if err := h.AuthService.RevokeAllForUser(userID); err != nil {
+		log.Printf("Error revoking tokens of deleted user: %v", err)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully!"})
+}
+
+// ChangePassword changes the authenticated user's password after verifying the current one.
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validatePartialUpdate(models.UpdateUserRequest{PasswordHash: &req.NewPassword}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.ChangePassword(userID, req.CurrentPassword, req.NewPassword); err != nil {
+		if writePasswordPolicyError(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidPassword) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Error changing password", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password changed successfully"})
+}
+
+// ListSessions returns the devices the authenticated user is signed in on,
+// marking the session used for the request.
+func (h *UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.AuthService.ListSessions(userID)
+	if err != nil {
+		http.Error(w, "Error fetching sessions", http.StatusInternalServerError)
+		return
+	}
+
+	currentID, _ := middleware.SessionIDFromContext(r.Context())
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession signs the authenticated user out of one device.
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := strconv.Atoi(mux.Vars(r)["sessionID"])
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AuthService.RevokeSession(userID, sessionID); err != nil {
+		if errors.Is(err, repositories.ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error revoking session", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Session revoked successfully"})
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-crud/internal/services"
+	"go-crud/middleware"
+	"log"
+	"net/http"
+)
+
+// mfaCodeRequest carries a TOTP code or a recovery code.
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// EnrollTOTP starts TOTP enrollment for the authenticated user and returns the
+// secret with its otpauth:// URI for display as a QR code.
+func (h *UserHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.Service.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	secret, uri, err := h.MFA.BeginEnrollment(user)
+	if err != nil {
+		if errors.Is(err, services.ErrMFAAlreadyEnabled) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "Error enrolling two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	})
+}
+
+// ConfirmTOTP enables TOTP using a first code from the authenticator app and
+// returns the recovery codes. They are shown only once.
+func (h *UserHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.MFA.ConfirmEnrollment(userID, req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string][]string{"recovery_codes": codes})
+}
+
+// DisableTOTP turns off TOTP after verifying a current code or recovery code.
+func (h *UserHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var req mfaCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.MFA.Disable(userID, req.Code); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Two-factor authentication disabled"})
+}
+
+// LoginMFA completes a two-step login by exchanging the challenge token from
+// Login and a valid code for a token pair. Each challenge can be redeemed only
+// once, whether the code is accepted or not, and wrong codes count as failed
+// logins against the account so that guessing codes leads to a lockout.
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := h.Tokens.ParseMFAChallengeToken(req.MFAToken)
+	if err != nil {
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+		return
+	}
+	consumed, err := h.AuthService.Revocations.ConsumeToken(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		http.Error(w, "Error verifying code", http.StatusInternalServerError)
+		return
+	}
+	if !consumed {
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+		return
+	}
+	userID, _ := claims.UserID()
+
+	user, err := h.Service.GetUserByID(userID)
+	if err != nil {
+		http.Error(w, "Error fetching user", http.StatusInternalServerError)
+		return
+	}
+
+	ip := clientIP(r)
+	wait, err := h.LoginThrottle.Attempt(user.Email, ip)
+	if err != nil {
+		http.Error(w, "Error checking login attempts", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		writeLoginThrottled(w, wait)
+		return
+	}
+
+	if err := h.MFA.Verify(userID, req.Code); err != nil {
+		if errors.Is(err, services.ErrInvalidMFACode) || errors.Is(err, services.ErrMFANotEnrolled) {
+			http.Error(w, services.ErrInvalidMFACode.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Error verifying code", http.StatusInternalServerError)
+		return
+	}
+	if err := h.LoginThrottle.RecordSuccess(user.Email, ip); err != nil {
+		log.Printf("Error clearing login attempts: %v", err)
+	}
+
+	tokens, err := h.AuthService.IssueTokens(user, r.UserAgent(), ip)
+	if err != nil {
+		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// writeMFAError maps MFA service errors to HTTP responses.
+func writeMFAError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrMFANotEnrolled):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Error processing two-factor authentication", http.StatusInternalServerError)
+	}
+}
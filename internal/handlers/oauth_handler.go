@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/services"
+	"go-crud/middleware"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type OAuthHandler struct {
+	OAuth *services.OAuthService
+}
+
+func NewOAuthHandler(oauth *services.OAuthService) *OAuthHandler {
+	return &OAuthHandler{OAuth: oauth}
+}
+
+// RegisterOAuthRoutes registers the OAuth 2.0 authorization server endpoints
+// and the administrative routes to manage clients.
+func RegisterOAuthRoutes(router *mux.Router, oauth *services.OAuthService, authMiddleware mux.MiddlewareFunc) {
+	handler := NewOAuthHandler(oauth)
+
+	// The user approving the request must be signed in with a first-party token of their own
+	user := func(h http.HandlerFunc) http.Handler {
+		return authMiddleware(middleware.DenyScopedCredentials(middleware.DenyImpersonation(h)))
+	}
+	router.Handle("/oauth/authorize", user(handler.Authorize)).Methods("GET")
+	router.Handle("/oauth/authorize", user(handler.ApproveAuthorization)).Methods("POST")
+	router.HandleFunc("/oauth/token", handler.Token).Methods("POST")
+	router.HandleFunc("/oauth/introspect", handler.Introspect).Methods("POST")
+	router.HandleFunc("/oauth/revoke", handler.Revoke).Methods("POST")
+
+	adminRouter := router.PathPrefix("/admin/oauth").Subrouter()
+	adminRouter.Use(authMiddleware, middleware.DenyScopedCredentials, middleware.DenyImpersonation, middleware.RequireRole(models.RoleAdmin))
+
+	adminRouter.HandleFunc("/clients", handler.ListClients).Methods("GET")
+	adminRouter.HandleFunc("/clients", handler.CreateClient).Methods("POST")
+	adminRouter.HandleFunc("/clients/{clientID}", handler.DeleteClient).Methods("DELETE")
+}
+
+// Authorize issues an authorization code to the client for the authenticated
+// user and redirects back to the client (RFC 6749 section 4.1). Until the user
+// approved the client for the requested scope, it responds with a consent
+// prompt instead, which the user answers through ApproveAuthorization.
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	h.authorize(w, r, false)
+}
+
+// ApproveAuthorization records the user's answer to the consent prompt of
+// Authorize, given by consent=approve or consent=deny along with the
+// parameters of the authorization request, and redirects back to the client.
+func (h *OAuthHandler) ApproveAuthorization(w http.ResponseWriter, r *http.Request) {
+	h.authorize(w, r, true)
+}
+
+func (h *OAuthHandler) authorize(w http.ResponseWriter, r *http.Request, answered bool) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, middleware.ErrInvalidToken.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req := services.AuthorizeRequest{
+		ResponseType:        r.Form.Get("response_type"),
+		ClientID:            r.Form.Get("client_id"),
+		RedirectURI:         r.Form.Get("redirect_uri"),
+		Scope:               r.Form.Get("scope"),
+		State:               r.Form.Get("state"),
+		CodeChallenge:       r.Form.Get("code_challenge"),
+		CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+	}
+
+	// Without a valid client and redirect URI there is nowhere safe to send errors
+	client, redirectURI, err := h.OAuth.ResolveRedirectURI(req.ClientID, req.RedirectURI)
+	if err != nil {
+		var oauthErr *services.OAuthError
+		if errors.As(err, &oauthErr) {
+			http.Error(w, oauthErr.Description, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Error processing authorization request", http.StatusInternalServerError)
+		return
+	}
+
+	if answered {
+		switch r.Form.Get("consent") {
+		case "approve":
+			err = h.OAuth.GrantConsent(userID, client, req.Scope)
+		case "deny":
+			err = &services.OAuthError{Code: "access_denied", Description: "the user denied the request"}
+		default:
+			http.Error(w, "consent must be approve or deny", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var code string
+	if err == nil {
+		code, err = h.OAuth.Authorize(userID, client, redirectURI, req)
+	}
+	if errors.Is(err, services.ErrConsentRequired) && !answered {
+		scope := req.Scope
+		if scope == "" {
+			scope = strings.Join(client.Scopes, " ")
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(models.OAuthConsentPrompt{
+			ClientID:   client.ClientID,
+			ClientName: client.Name,
+			Scope:      scope,
+			Message:    "Approve the request with POST /oauth/authorize and consent=approve",
+		})
+		return
+	}
+
+	params := url.Values{}
+	if err != nil {
+		var oauthErr *services.OAuthError
+		if !errors.As(err, &oauthErr) {
+			log.Printf("Error authorizing OAuth client: %v", err)
+			oauthErr = &services.OAuthError{Code: "server_error", Description: "internal error"}
+		}
+		params.Set("error", oauthErr.Code)
+		params.Set("error_description", oauthErr.Description)
+	} else {
+		params.Set("code", code)
+	}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	target, _ := url.Parse(redirectURI)
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// Token implements the token endpoint for the authorization_code,
+// refresh_token and client_credentials grants (RFC 6749 section 3.2).
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	var (
+		tokens models.OAuthTokenResponse
+		err    error
+	)
+	switch r.PostForm.Get("grant_type") {
+	case models.GrantAuthorizationCode:
+		tokens, err = h.OAuth.ExchangeAuthorizationCode(client, r.PostForm.Get("code"), r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"))
+	case models.GrantRefreshToken:
+		tokens, err = h.OAuth.RefreshToken(client, r.PostForm.Get("refresh_token"), r.PostForm.Get("scope"))
+	case models.GrantClientCredentials:
+		tokens, err = h.OAuth.ClientCredentials(client, r.PostForm.Get("scope"))
+	default:
+		err = &services.OAuthError{Code: "unsupported_grant_type", Description: "unsupported grant_type"}
+	}
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Introspect reports whether a token is active (RFC 7662).
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := h.OAuth.Introspect(client, r.PostForm.Get("token"))
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Revoke revokes a token issued to the client (RFC 7009). The response is the
+// same whether or not the token was valid.
+func (h *OAuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.OAuth.Revoke(client, r.PostForm.Get("token")); err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *OAuthHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.OAuth.ListClients()
+	if err != nil {
+		http.Error(w, "Error fetching OAuth clients", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(clients)
+}
+
+// CreateClient registers an OAuth client. The client secret is only included
+// in this response.
+func (h *OAuthHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateOAuthClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := models.Validate.Struct(req); err != nil {
+		http.Error(w, validationMessage(err), http.StatusBadRequest)
+		return
+	}
+
+	client, secret, err := h.OAuth.CreateClient(req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidClientMetadata) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Error creating OAuth client", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreateOAuthClientResponse{OAuthClient: client, ClientSecret: secret})
+}
+
+// DeleteClient deletes an OAuth client with its codes and refresh tokens.
+func (h *OAuthHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	if err := h.OAuth.DeleteClient(mux.Vars(r)["clientID"]); err != nil {
+		if errors.Is(err, repositories.ErrOAuthClientNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error deleting OAuth client", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "OAuth client deleted successfully"})
+}
+
+// authenticateClient parses the form and authenticates the client with HTTP
+// Basic credentials or client_id and client_secret form parameters (RFC 6749
+// section 2.3.1). It writes the error response and returns false on failure.
+func (h *OAuthHandler) authenticateClient(w http.ResponseWriter, r *http.Request) (models.OAuthClient, bool) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, &services.OAuthError{Code: "invalid_request", Description: "invalid form body"})
+		return models.OAuthClient{}, false
+	}
+
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		// Basic credentials are form-encoded before being base64-encoded
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	client, err := h.OAuth.AuthenticateClient(clientID, secret)
+	if err != nil {
+		if basic && errors.Is(err, services.ErrInvalidClient) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthError(w, err)
+		return models.OAuthClient{}, false
+	}
+	return client, true
+}
+
+// writeOAuthError writes an OAuth error response (RFC 6749 section 5.2).
+func writeOAuthError(w http.ResponseWriter, err error) {
+	var oauthErr *services.OAuthError
+	status := http.StatusBadRequest
+	if !errors.As(err, &oauthErr) {
+		log.Printf("Error processing OAuth request: %v", err)
+		oauthErr = &services.OAuthError{Code: "server_error", Description: "internal error"}
+		status = http.StatusInternalServerError
+	} else if oauthErr.Code == services.ErrInvalidClient.Code {
+		status = http.StatusUnauthorized
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": oauthErr.Code, "error_description": oauthErr.Description})
+}
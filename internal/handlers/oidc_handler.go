@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"go-crud/internal/services"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// oidcStateCookie binds a login with an external provider to the browser that
+// started it, so a callback can't be replayed in another browser (login CSRF).
+const oidcStateCookie = "oidc_state"
+
+// BeginOIDCLogin redirects the user to the external identity provider.
+func (h *AuthHandler) BeginOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.OIDC.BeginLogin(mux.Vars(r)["provider"])
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownOIDCProvider) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Error starting OIDC login: %v", err)
+		http.Error(w, "Error starting login", http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/login/oidc/",
+		MaxAge:   int(services.OIDCLoginStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode, // Sent on the top-level redirect back from the provider
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback completes a login with the external identity provider and
+// continues like Login, including the MFA challenge.
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	state := query.Get("state")
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		http.Error(w, services.ErrInvalidOIDCState.Error(), http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/login/oidc/", MaxAge: -1})
+
+	if providerError := query.Get("error"); providerError != "" {
+		http.Error(w, "Login failed at the identity provider: "+providerError, http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.OIDC.CompleteLogin(mux.Vars(r)["provider"], state, query.Get("code"))
+	if err != nil {
+		var linkRequired *services.ErrOIDCLinkRequired
+		switch {
+		case errors.As(err, &linkRequired):
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"message": err.Error(), "link_token": linkRequired.LinkToken})
+		case errors.Is(err, services.ErrUnknownOIDCProvider):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, services.ErrInvalidOIDCState):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrInvalidIDToken):
+			log.Printf("Error verifying OIDC login: %v", err)
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		case errors.Is(err, services.ErrOIDCEmailNotVerified), errors.Is(err, services.ErrOIDCAccountNotFound),
+			errors.Is(err, services.ErrOIDCAccountNotVerified):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			log.Printf("Error completing OIDC login: %v", err)
+			http.Error(w, "Error completing login", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	wait, err := h.LoginThrottle.Attempt(user.Email, clientIP(r))
+	if err != nil {
+		http.Error(w, "Error checking login attempts", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		writeLoginThrottled(w, wait)
+		return
+	}
+
+	h.completeLogin(w, r, user)
+}
+
+// LinkOIDCIdentity links an external identity to the existing account with the
+// same email once the account's password is confirmed, then continues like
+// Login. Wrong passwords count as failed logins of the account.
+func (h *AuthHandler) LinkOIDCIdentity(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		LinkToken string `json:"link_token"`
+		Password  string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.LinkToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.OIDC.TakePendingLink(req.LinkToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidOIDCLink) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Error linking account", http.StatusInternalServerError)
+		return
+	}
+
+	ip := clientIP(r)
+	wait, err := h.LoginThrottle.Attempt(link.Email, ip)
+	if err != nil {
+		http.Error(w, "Error checking login attempts", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		writeLoginThrottled(w, wait)
+		return
+	}
+
+	user, err := h.OIDC.ConfirmLink(link, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidOIDCLinkPassword):
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		case errors.Is(err, services.ErrInvalidOIDCLink):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrOIDCAccountNotVerified):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			log.Printf("Error linking OIDC identity: %v", err)
+			http.Error(w, "Error linking account", http.StatusInternalServerError)
+		}
+		return
+	}
+	h.completeLogin(w, r, user)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-crud/internal/models"
+	"go-crud/internal/services"
+	"log"
+	"net/http"
+)
+
+// ForgotPassword emails a password reset token. It always responds with 202
+// so the response doesn't reveal whether the email belongs to an account; the
+// token is sent in the background so the response time doesn't either.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	go func() {
+		if err := h.PasswordResets.RequestReset(req.Email); err != nil {
+			log.Printf("Error requesting password reset: %v", err)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "If the account exists, a reset email has been sent"})
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword.
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := validatePartialUpdate(models.UpdateUserRequest{PasswordHash: &req.Password}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.PasswordResets.ResetPassword(req.Token, req.Password); err != nil {
+		if writePasswordPolicyError(w, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Error resetting password", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password reset successfully"})
+}
+
+// writePasswordPolicyError writes a 400 listing the rules the new password
+// violates, and reports whether err was a password policy error.
+func writePasswordPolicyError(w http.ResponseWriter, err error) bool {
+	var policyErr *services.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	http.Error(w, policyErr.Error(), http.StatusBadRequest)
+	return true
+}
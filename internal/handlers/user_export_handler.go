@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"go-crud/internal/services"
+	"log"
+	"net/http"
+)
+
+// exportContentTypes maps the export formats to their media types.
+var exportContentTypes = map[string]string{
+	services.ExportFormatCSV:     "text/csv; charset=utf-8",
+	services.ExportFormatNDJSON:  "application/x-ndjson",
+	services.ExportFormatParquet: "application/vnd.apache.parquet",
+}
+
+// ExportUsers streams every user matching the GET /users filters in the
+// format given by the format query parameter: csv (the default), ndjson or
+// parquet.
+func (h *UserHandler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseUserFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.ExportFormatCSV
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		http.Error(w, services.ErrUnknownExportFormat.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="users.`+format+`"`)
+	export, err := services.NewUserExportWriter(format, w)
+	if err == nil {
+		err = h.Service.ExportUsers(filter, export)
+	}
+	if err != nil {
+		// The response may have started; aborting it makes sure the client
+		// doesn't take the partial export for a complete one
+		log.Printf("Error exporting users: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
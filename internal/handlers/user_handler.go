@@ -3,15 +3,21 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	_ "fmt"
 	"github.com/go-playground/validator/v10"
 	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
 	"go-crud/middleware"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"go-crud/internal/models"
@@ -33,47 +39,274 @@ func validateUser(user models.User) (string, bool) {
 }
 
 type UserHandler struct {
-	Service *services.UserService
+	Service       *services.UserService
+	AuthService   *services.AuthService
+	MFA           *services.MFAService
+	LoginThrottle *services.LoginThrottleService
+	APIKeys       *services.APIKeyService
+	Imports       *services.UserImportService
+	// RequireIfMatch rejects user updates and deletes without an If-Match header
+	RequireIfMatch bool
 }
 
-func NewUserHandler(service *services.UserService) *UserHandler {
-	return &UserHandler{Service: service}
+func NewUserHandler(service *services.UserService, authService *services.AuthService, mfa *services.MFAService,
+	loginThrottle *services.LoginThrottleService, apiKeys *services.APIKeyService, imports *services.UserImportService,
+	requireIfMatch bool) *UserHandler {
+	return &UserHandler{Service: service, AuthService: authService, MFA: mfa, LoginThrottle: loginThrottle, APIKeys: apiKeys,
+		Imports: imports, RequireIfMatch: requireIfMatch}
 }
 
-func RegisterUserRoutes(router *mux.Router, db *sql.DB, secretKey []byte) {
+func RegisterUserRoutes(router *mux.Router, db *sql.DB, authService *services.AuthService, mfa *services.MFAService,
+	loginThrottle *services.LoginThrottleService, apiKeys *services.APIKeyService, passwords *services.PasswordPolicyService,
+	emailVerifications *services.EmailVerificationService, imports *services.UserImportService, requireIfMatch bool,
+	authMiddleware mux.MiddlewareFunc) {
 	repo := repositories.NewUserRepository(db)
-	service := services.NewUserService(repo)
-	handler := NewUserHandler(service)
+	service := services.NewUserService(repo, passwords)
+	service.Verifications = emailVerifications
+	handler := NewUserHandler(service, authService, mfa, loginThrottle, apiKeys, imports, requireIfMatch)
 
 	// Apply AuthMiddleware to all /users routes
 	protectedRouter := router.PathPrefix("/users").Subrouter()
-	protectedRouter.Use(middleware.AuthMiddleware(secretKey)) // Protect all /users routes
+	protectedRouter.Use(authMiddleware) // Protect all /users routes
 
-	protectedRouter.HandleFunc("", handler.GetUsers).Methods("GET")
-	protectedRouter.HandleFunc("/{id}", handler.GetUser).Methods("GET")
-	protectedRouter.HandleFunc("", handler.CreateUser).Methods("POST")
-	protectedRouter.HandleFunc("/{id}", handler.UpdateUser).Methods("PUT")
-	protectedRouter.HandleFunc("/{id}", handler.DeleteUser).Methods("DELETE")
+	adminOnly := middleware.RequireRole(models.RoleAdmin)
+	ownerOrAdmin := middleware.RequireSelfOrRole("id", models.RoleAdmin)
+	// API keys additionally need the matching scope
+	canRead := middleware.RequireScope(models.ScopeUsersRead)
+	canWrite := middleware.RequireScope(models.ScopeUsersWrite)
+
+	protectedRouter.Handle("", canRead(adminOnly(http.HandlerFunc(handler.GetUsers)))).Methods("GET")
+	protectedRouter.Handle("/search", canRead(adminOnly(http.HandlerFunc(handler.SearchUsers)))).Methods("GET")
+	protectedRouter.Handle("/export", canRead(adminOnly(http.HandlerFunc(handler.ExportUsers)))).Methods("GET")
+	protectedRouter.Handle("/{id}", canRead(ownerOrAdmin(http.HandlerFunc(handler.GetUser)))).Methods("GET")
+	protectedRouter.Handle("", canWrite(adminOnly(http.HandlerFunc(handler.CreateUser)))).Methods("POST")
+	protectedRouter.Handle("/import", canWrite(adminOnly(http.HandlerFunc(handler.ImportUsers)))).Methods("POST")
+	protectedRouter.Handle("/{id}", canWrite(ownerOrAdmin(http.HandlerFunc(handler.UpdateUser)))).Methods("PUT")
+	protectedRouter.Handle("/{id}", canWrite(ownerOrAdmin(http.HandlerFunc(handler.PatchUser)))).Methods("PATCH")
+	protectedRouter.Handle("/{id}", canWrite(adminOnly(middleware.DenyImpersonation(http.HandlerFunc(handler.DeleteUser))))).Methods("DELETE")
+	protectedRouter.Handle("/{id}/restore", canWrite(adminOnly(http.HandlerFunc(handler.RestoreUser)))).Methods("POST")
+
+	// Credential management is not available to API keys, OAuth clients or impersonators
+	credentials := func(h http.HandlerFunc) http.Handler {
+		return middleware.DenyScopedCredentials(middleware.DenyImpersonation(ownerOrAdmin(h)))
+	}
+	protectedRouter.Handle("/{id}/tokens/revoke", credentials(handler.RevokeUserTokens)).Methods("POST")
+	protectedRouter.Handle("/{id}/api-keys", credentials(handler.ListAPIKeys)).Methods("GET")
+	protectedRouter.Handle("/{id}/api-keys", credentials(handler.CreateAPIKey)).Methods("POST")
+	protectedRouter.Handle("/{id}/api-keys/{keyID}", credentials(handler.GetAPIKey)).Methods("GET")
+	protectedRouter.Handle("/{id}/api-keys/{keyID}", credentials(handler.UpdateAPIKey)).Methods("PATCH")
+	protectedRouter.Handle("/{id}/api-keys/{keyID}", credentials(handler.DeleteAPIKey)).Methods("DELETE")
+
+	// Self-service routes for the authenticated user
+	meRouter := router.PathPrefix("/me").Subrouter()
+	meRouter.Use(authMiddleware, middleware.DenyScopedCredentials)
+
+	// Support staff impersonating the user can see and edit the profile, but
+	// not change the email, delete the account or change how the user signs in
+	sensitive := func(h http.HandlerFunc) http.Handler {
+		return middleware.DenyImpersonation(h)
+	}
+	meRouter.HandleFunc("", handler.GetMe).Methods("GET")
+	meRouter.HandleFunc("", handler.UpdateMe).Methods("PATCH")
+	meRouter.Handle("", sensitive(handler.DeleteMe)).Methods("DELETE")
+	meRouter.Handle("/password", sensitive(handler.ChangePassword)).Methods("PUT")
+	meRouter.Handle("/mfa/totp", sensitive(handler.EnrollTOTP)).Methods("POST")
+	meRouter.Handle("/mfa/totp/confirm", sensitive(handler.ConfirmTOTP)).Methods("POST")
+	meRouter.Handle("/mfa/totp", sensitive(handler.DisableTOTP)).Methods("DELETE")
+	meRouter.HandleFunc("/sessions", handler.ListSessions).Methods("GET")
+	meRouter.Handle("/sessions/{sessionID}", sensitive(handler.RevokeSession)).Methods("DELETE")
+
+	// Administrative routes
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(authMiddleware, middleware.DenyScopedCredentials, middleware.DenyImpersonation, adminOnly)
+
+	adminRouter.HandleFunc("/users/{id}/unlock", handler.UnlockUser).Methods("POST")
 }
 
+// GetUsers returns one page of users. The query string selects the page
+// (limit with cursor or offset), the order (sort, order) and the filters
+// (name_prefix, email_domain, include_deleted). Links to the other pages are
+// returned in the Link header, and with include_total=true the number of
+// matching users in X-Total-Count.
 func (h *UserHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.Service.GetAllUsers()
+	query, err := parseListUsersQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.Service.ListUsers(query)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) || errors.Is(err, services.ErrCursorWithOffset) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	json.NewEncoder(w).Encode(users)
+
+	if links := paginationLinks(r.URL, query, page); len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	if page.Total != nil {
+		w.Header().Set("X-Total-Count", strconv.Itoa(*page.Total))
+	}
+	json.NewEncoder(w).Encode(page.Users)
+}
+
+// SearchUsers finds users by partial name or email with ?q=, returning at
+// most ?limit= results ranked by relevance.
+func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > models.MaxUserSearchLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", models.MaxUserSearchLimit), http.StatusBadRequest)
+			return
+		}
+	}
+
+	results, err := h.Service.SearchUsers(r.URL.Query().Get("q"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrSearchTooShort) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Error searching users", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(results)
+}
+
+// parseListUsersQuery parses and validates the query string of GET /users.
+func parseListUsersQuery(values url.Values) (models.ListUsersQuery, error) {
+	filter, err := parseUserFilter(values)
+	if err != nil {
+		return models.ListUsersQuery{}, err
+	}
+	query := models.ListUsersQuery{
+		UserFilter: filter,
+		Sort:       values.Get("sort"),
+		Order:      values.Get("order"),
+		Cursor:     values.Get("cursor"),
+	}
+
+	if value := values.Get("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit == 0 {
+			return query, fmt.Errorf("limit must be between 1 and %d", models.MaxUserPageSize)
+		}
+	}
+	if value := values.Get("offset"); value != "" {
+		if query.Offset, err = strconv.Atoi(value); err != nil {
+			return query, errors.New("offset must be a non-negative integer")
+		}
+	}
+	if value := values.Get("include_total"); value != "" {
+		if query.IncludeTotal, err = strconv.ParseBool(value); err != nil {
+			return query, errors.New("include_total must be true or false")
+		}
+	}
+
+	if err := models.Validate.Struct(query); err != nil {
+		return query, errors.New(validationMessage(err))
+	}
+	return query, nil
 }
 
+// parseUserFilter parses the filters shared by GET /users and GET /users/export:
+// name_prefix, email_domain and include_deleted.
+func parseUserFilter(values url.Values) (models.UserFilter, error) {
+	filter := models.UserFilter{
+		NamePrefix:  values.Get("name_prefix"),
+		EmailDomain: values.Get("email_domain"),
+	}
+	var err error
+	filter.IncludeDeleted, err = boolParam(values, "include_deleted")
+	return filter, err
+}
+
+// paginationLinks returns the Link header values (RFC 8288) for the pages
+// next to the current one, keeping the other query parameters.
+func paginationLinks(current *url.URL, query models.ListUsersQuery, page models.UserPage) []string {
+	link := func(rel string, set map[string]string) string {
+		values := current.Query()
+		for key, value := range set {
+			if value == "" {
+				values.Del(key)
+			} else {
+				values.Set(key, value)
+			}
+		}
+		target := url.URL{Path: current.Path, RawQuery: values.Encode()}
+		return fmt.Sprintf("<%s>; rel=\"%s\"", target.String(), rel)
+	}
+
+	var links []string
+	if query.Cursor != "" || query.Offset > 0 {
+		links = append(links, link("first", map[string]string{"cursor": "", "offset": ""}))
+	}
+	if query.Cursor == "" && query.Offset > 0 {
+		limit := query.Limit
+		if limit == 0 {
+			limit = models.DefaultUserPageSize
+		}
+		prev := ""
+		if query.Offset > limit {
+			prev = strconv.Itoa(query.Offset - limit)
+		}
+		links = append(links, link("prev", map[string]string{"offset": prev}))
+	}
+	if page.NextCursor != "" {
+		if query.Offset > 0 {
+			// Offset pagination continues with offsets
+			links = append(links, link("next", map[string]string{"offset": strconv.Itoa(query.Offset + len(page.Users))}))
+		} else {
+			links = append(links, link("next", map[string]string{"cursor": page.NextCursor}))
+		}
+	}
+	return links
+}
+
+// boolParam parses a boolean query parameter, false when absent.
+func boolParam(values url.Values, name string) (bool, error) {
+	value := values.Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", name)
+	}
+	return b, nil
+}
+
+// GetUser returns a user. Admins can look up deleted users that haven't been
+// purged yet with include_deleted=true.
 func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id, _ := strconv.Atoi(params["id"])
-	user, err := h.Service.GetUserByID(id)
+
+	withDeleted, err := boolParam(r.URL.Query(), "include_deleted")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if role, _ := middleware.RoleFromContext(r.Context()); withDeleted && role != models.RoleAdmin {
+		http.Error(w, "Only admins can include deleted users", http.StatusForbidden)
+		return
+	}
+
+	var user models.User
+	if withDeleted {
+		user, err = h.Service.GetUserByIDIncludingDeleted(id)
+	} else {
+		user, err = h.Service.GetUserByID(id)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
-	json.NewEncoder(w).Encode(user)
+	writeUserWithETag(w, r, user)
 }
 
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -91,12 +324,17 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	newUser, err := h.Service.CreateUser(user)
 	if err != nil {
+		if writePasswordPolicyError(w, err) {
+			return
+		}
 		http.Error(w, "Error creating user", http.StatusInternalServerError)
 		return
 	}
 	json.NewEncoder(w).Encode(newUser)
 }
 
+// UpdateUser replaces the user's profile. Every profile field is required;
+// PatchUser changes some of them.
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id, err := strconv.Atoi(params["id"])
@@ -105,36 +343,243 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var updateUserReq models.UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&updateUserReq); err != nil {
+	var replaceUserReq models.ReplaceUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&replaceUserReq); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if err := validatePartialUpdate(updateUserReq); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if _, impersonating := middleware.ActorIDFromContext(r.Context()); impersonating {
+		if replaceUserReq.PasswordHash != nil {
+			http.Error(w, "Passwords can't be changed while impersonating", http.StatusForbidden)
+			return
+		}
+		current, err := h.Service.GetUserByID(id)
+		if err != nil {
+			if errors.Is(err, repositories.ErrUserNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Error fetching user", http.StatusInternalServerError)
+			return
+		}
+		if replaceUserReq.Email != current.Email {
+			http.Error(w, errEmailChangeWhileImpersonating.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	ifMatch, ok := h.preconditions(w, r)
+	if !ok {
 		return
 	}
 
-	if err := h.Service.UpdateUser(id, updateUserReq); err != nil {
+	user, err := h.Service.ReplaceUser(id, replaceUserReq, ifMatch)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			http.Error(w, validationMessage(err), http.StatusBadRequest)
+			return
+		}
+		if writePasswordPolicyError(w, err) || writeVersionConflict(w, err) {
+			return
+		}
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "Error updating user", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("ETag", userETag(user))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "User updated successfully"})
 }
 
+// Media types of the patch documents accepted by PatchUser.
+const (
+	mergePatchMediaType = "application/merge-patch+json"
+	jsonPatchMediaType  = "application/json-patch+json"
+)
+
+// maxPatchSize limits the size of patch documents.
+const maxPatchSize = 1 << 20
+
+// errEmailChangeWhileImpersonating rejects email changes by support staff, who
+// could otherwise take over the account through a password reset.
+var errEmailChangeWhileImpersonating = errors.New("email can't be changed while impersonating")
+
+// keepEmail wraps patch so that it fails when the patched document has a
+// different email than the original.
+func keepEmail(patch func(document []byte) ([]byte, error)) func(document []byte) ([]byte, error) {
+	return func(document []byte) ([]byte, error) {
+		patched, err := patch(document)
+		if err != nil {
+			return nil, err
+		}
+		var before, after struct {
+			Email string `json:"email"`
+		}
+		if json.Unmarshal(document, &before) != nil || json.Unmarshal(patched, &after) != nil ||
+			before.Email != after.Email {
+			return nil, errEmailChangeWhileImpersonating
+		}
+		return patched, nil
+	}
+}
+
+// PatchUser applies a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902)
+// to the user, depending on the Content-Type, and returns the patched user.
+func (h *UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var patch func(document []byte) ([]byte, error)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mergePatchMediaType:
+		patch = func(document []byte) ([]byte, error) { return utils.MergePatch(document, body) }
+	case jsonPatchMediaType:
+		patch = func(document []byte) ([]byte, error) { return utils.ApplyJSONPatch(document, body) }
+	default:
+		w.Header().Set("Accept-Patch", mergePatchMediaType+", "+jsonPatchMediaType)
+		http.Error(w, "Unsupported patch format", http.StatusUnsupportedMediaType)
+		return
+	}
+	if _, impersonating := middleware.ActorIDFromContext(r.Context()); impersonating {
+		patch = keepEmail(patch)
+	}
+
+	ifMatch, ok := h.preconditions(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.Service.PatchUser(id, patch, ifMatch)
+	if err != nil {
+		var validationErrors validator.ValidationErrors
+		switch {
+		case errors.Is(err, errEmailChangeWhileImpersonating):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.As(err, &validationErrors):
+			http.Error(w, validationMessage(err), http.StatusUnprocessableEntity)
+		case errors.Is(err, utils.ErrInvalidPatch):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, utils.ErrPatchTestFailed):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, utils.ErrPatchConflict), errors.Is(err, services.ErrReadOnlyField),
+			errors.Is(err, services.ErrInvalidPatchedUser):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(err, repositories.ErrVersionConflict), errors.Is(err, services.ErrConcurrentUpdate):
+			writeVersionConflict(w, err)
+		case errors.Is(err, repositories.ErrUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, "Error updating user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", userETag(user))
+	json.NewEncoder(w).Encode(user)
+}
+
+// DeleteUser soft-deletes a user and signs them out everywhere. The account
+// can be restored until it is purged.
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id, _ := strconv.Atoi(params["id"])
-	if err := h.Service.DeleteUser(id); err != nil {
+	ifMatch, ok := h.preconditions(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Service.DeleteUser(id, ifMatch); err != nil {
+		if writeVersionConflict(w, err) {
+			return
+		}
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, "Error deleting user", http.StatusInternalServerError)
 		return
 	}
+	if err := h.AuthService.RevokeAllForUser(id); err != nil {
+		log.Printf("Error revoking tokens of deleted user: %v", err)
+	}
 	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully!"})
 }
 
+// RestoreUser restores a deleted user that hasn't been purged yet.
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.RestoreUser(id); err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrUserNotFound):
+			http.Error(w, "Deleted user not found", http.StatusNotFound)
+		case errors.Is(err, repositories.ErrEmailInUse):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Error restoring user", http.StatusInternalServerError)
+		}
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "User restored successfully"})
+}
+
+// RevokeUserTokens revokes every outstanding access and refresh token of a user.
+func (h *UserHandler) RevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AuthService.RevokeAllForUser(id); err != nil {
+		http.Error(w, "Error revoking tokens", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "All tokens revoked successfully"})
+}
+
+// UnlockUser clears the failed login counter of a user, lifting any lockout.
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := strconv.Atoi(params["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Service.GetUserByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := h.LoginThrottle.Unlock(user.Email); err != nil {
+		http.Error(w, "Error unlocking user", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "User unlocked successfully"})
+}
+
 // validatePartialUpdate validates the fields provided in the UpdateUserRequest.
 func validatePartialUpdate(req models.UpdateUserRequest) error {
 	if req.Name != nil && len(*req.Name) < 2 {
@@ -1,10 +1,22 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"github.com/golang/mock/gomock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
 	"go-crud/internal/services"
+	"go-crud/internal/utils"
+	"go-crud/middleware"
 )
 
 func TestGetAllUsersHandler_Success(t *testing.T) {
@@ -13,4 +25,118 @@ func TestGetAllUsersHandler_Success(t *testing.T) {
 
 	//Create a mock service
 	mockService := services.NewMockUserServiceInterface(ctrl)
+	_ = mockService
+}
+
+// notRevoked accepts every token.
+type notRevoked struct{}
+
+func (notRevoked) IsRevoked(string, int, int, time.Time) (bool, error) { return false, nil }
+
+// serveImpersonated routes the request to the user handlers with a token
+// issued to user 1 while impersonated by user 3.
+func serveImpersonated(t *testing.T, repo repositories.UserRepositoryInterface, method, target, contentType, body string) *httptest.ResponseRecorder {
+	tokens := utils.NewTokenService(utils.NewHMACKeySet([]byte("test-secret")), "", "")
+	token, _, _, err := tokens.IssueImpersonationToken(1, models.RoleUser, 3)
+	assert.NoError(t, err)
+
+	handler := &UserHandler{Service: services.NewUserService(repo, nil)}
+	auth := middleware.AuthMiddleware(tokens, notRevoked{}, nil)
+	router := mux.NewRouter()
+	router.Handle("/users/{id}", auth(http.HandlerFunc(handler.UpdateUser))).Methods("PUT")
+	router.Handle("/users/{id}", auth(http.HandlerFunc(handler.PatchUser))).Methods("PATCH")
+	router.Handle("/me", auth(http.HandlerFunc(handler.UpdateMe))).Methods("PATCH")
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpdateUserHandler_ImpersonatorCantChangeEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+
+	// Mock repository behavior: the user is never written
+	mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Name: "Alice", Email: "alice@example.com"}, nil)
+
+	// Call the handler
+	rec := serveImpersonated(t, mockRepo, "PUT", "/users/1", "application/json",
+		`{"name": "Alice", "email": "mallory@example.com"}`)
+
+	// Assertions
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestPatchUserHandler_ImpersonatorCantChangeEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+
+	// Mock repository behavior: the user is never written
+	mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Name: "Alice", Email: "alice@example.com"}, nil)
+
+	// Call the handler
+	rec := serveImpersonated(t, mockRepo, "PATCH", "/users/1", mergePatchMediaType,
+		`{"email": "mallory@example.com"}`)
+
+	// Assertions
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestUpdateMeHandler_ImpersonatorCantChangeEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository that expects no calls
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+
+	// Call the handler
+	rec := serveImpersonated(t, mockRepo, "PATCH", "/me", "application/json",
+		`{"email": "mallory@example.com"}`)
+
+	// Assertions
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestImportUsersHandler_ReturnsPartialReportOnError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	imports := services.NewUserImportService(mockRepo, nil)
+	imports.Workers, imports.BatchSize = 1, 1
+	handler := &UserHandler{Imports: imports}
+
+	// Mock repository behavior: the database fails on the second batch
+	gomock.InOrder(
+		mockRepo.EXPECT().ExistingEmails([]string{"john@gmail.com"}).Return(nil, nil),
+		mockRepo.EXPECT().ImportUsers(gomock.Len(1)).Return(map[string]int{"john@gmail.com": 1}, nil),
+		mockRepo.EXPECT().ExistingEmails([]string{"jane@gmail.com"}).Return(nil, errors.New("connection reset")),
+	)
+
+	// Call the handler
+	input := "name,email,password\n" +
+		"John,john@gmail.com,secret-password\n" +
+		"Jane,jane@gmail.com,secret-password\n"
+	req := httptest.NewRequest(http.MethodPost, "/users/import", strings.NewReader(input))
+	req.Header.Set("Content-Type", "text/csv")
+	rr := httptest.NewRecorder()
+	handler.ImportUsers(rr, req)
+
+	// Assertions: the user created before the failure is reported
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	var report models.UserImportReport
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.Equal(t, 1, report.Succeeded)
+	assert.Equal(t, 1, report.Results[0].ID)
+	assert.NotEmpty(t, report.Aborted)
 }
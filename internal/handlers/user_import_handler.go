@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"go-crud/internal/services"
+	"log"
+	"mime"
+	"net/http"
+)
+
+// maxImportSize limits the size of bulk user imports.
+const maxImportSize = 64 << 20
+
+// ImportUsers creates users in bulk from a CSV (text/csv) or NDJSON
+// (application/x-ndjson) body and returns the result of every row. With
+// dry_run=true the rows are only validated. If the import stops early, the
+// report of the rows processed so far is returned with the error status, as
+// those rows have been created.
+func (h *UserHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	dryRun, err := boolParam(r.URL.Query(), "dry_run")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	var source services.UserImportSource
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		if source, err = services.NewCSVUserImportSource(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case "application/x-ndjson", "application/ndjson":
+		source = services.NewNDJSONUserImportSource(body)
+	default:
+		http.Error(w, "Imports must be text/csv or application/x-ndjson", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	report, err := h.Imports.Import(source, dryRun)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		status := http.StatusInternalServerError
+		switch {
+		case errors.As(err, &tooLarge):
+			status, report.Aborted = http.StatusRequestEntityTooLarge, "Import is too large"
+		case errors.Is(err, services.ErrImportRead):
+			status, report.Aborted = http.StatusBadRequest, err.Error()
+		default:
+			log.Printf("Error importing users after %d rows: %v", report.Rows, err)
+			report.Aborted = "Error importing users"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
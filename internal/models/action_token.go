@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Purposes of single-use action tokens.
+const (
+	PurposePasswordReset     = "password_reset"
+	PurposeEmailVerification = "email_verification"
+	PurposeMagicLink         = "magic_link"
+)
+
+// ActionToken is a single-use, expiring token sent to a user out of band,
+// e.g. by email. Only the hash of the token is stored.
+type ActionToken struct {
+	ID        int
+	UserID    int
+	Purpose   string
+	TokenHash string
+	Email     string // Address the token was sent to
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Scopes that can be granted to API keys. Requests authenticated with an API
+// key are limited to its scopes in addition to the owner's role.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+)
+
+// APIKey is a long-lived credential for machine-to-machine access. Only the
+// hash of the key is stored; the prefix identifies it in listings and logs.
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyRequest is the body of a request to create an API key.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=users:read users:write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// UpdateAPIKeyRequest is the body of a request to rename an API key or change its scopes.
+type UpdateAPIKeyRequest struct {
+	Name   *string   `json:"name" validate:"omitempty,min=1,max=100"`
+	Scopes *[]string `json:"scopes" validate:"omitnil,min=1,dive,oneof=users:read users:write"`
+}
+
+// CreateAPIKeyResponse includes the plaintext key, which is shown only once.
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ImpersonationSession is the audit record of a support staff member
+// impersonating a user. Sessions are kept after the users are deleted.
+type ImpersonationSession struct {
+	ID        int       `json:"id"`
+	ActorID   *int      `json:"actor_id"`
+	UserID    *int      `json:"user_id"`
+	Reason    string    `json:"reason"`
+	TokenID   string    `json:"token_id"` // jti of the impersonation token
+	IPAddress string    `json:"ip_address"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ImpersonateRequest is the body of POST /admin/impersonate/{id}.
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+// ImpersonationResponse carries an impersonation token. There is no refresh
+// token; a new session must be started when the token expires.
+type ImpersonationResponse struct {
+	Token     string `json:"token"`
+	ExpiresIn int64  `json:"expires_in"` // Token lifetime in seconds
+	SessionID int    `json:"session_id"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// LoginAttempt counts consecutive failed logins for a throttling key, such as
+// an account email or a client IP.
+type LoginAttempt struct {
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+}
+
+// ThrottlePolicy describes how failed logins for one kind of key are throttled.
+type ThrottlePolicy struct {
+	FreeAttempts    int           // Failures allowed before any delay is imposed
+	MaxFailures     int           // Failures after which the key is locked out
+	BaseDelay       time.Duration // Delay after the first throttled failure, doubled for each further failure
+	LockoutDuration time.Duration // Lockout after MaxFailures failures
+	ResetAfter      time.Duration // Counters restart when no failure happened for this long
+}
+
+// Delay returns how long after the last failure the next attempt is blocked.
+func (p ThrottlePolicy) Delay(failures int) time.Duration {
+	if failures < p.FreeAttempts {
+		return 0
+	}
+	if failures >= p.MaxFailures {
+		return p.LockoutDuration
+	}
+
+	delay := p.BaseDelay << (failures - p.FreeAttempts)
+	if delay <= 0 || delay > p.LockoutDuration {
+		return p.LockoutDuration
+	}
+	return delay
+}
+
+// Wait returns how long the attempt blocks further attempts, measured from now.
+func (p ThrottlePolicy) Wait(attempt LoginAttempt, now time.Time) time.Duration {
+	if attempt.Failures == 0 || now.Sub(attempt.LastFailureAt) > p.ResetAfter {
+		return 0
+	}
+
+	wait := attempt.LastFailureAt.Add(p.Delay(attempt.Failures)).Sub(now)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
@@ -0,0 +1,23 @@
+package models
+
+// TOTPSettings holds a user's TOTP enrollment. The secret is stored before the
+// enrollment is confirmed, with Enabled set to false.
+type TOTPSettings struct {
+	UserID       int
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64 // Last accepted time step, used to reject replayed codes
+}
+
+// RecoveryCode is a hashed single-use MFA recovery code.
+type RecoveryCode struct {
+	ID       int
+	UserID   int
+	CodeHash string
+}
+
+// MFAChallengeResponse is returned by /login when a second factor is required.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
@@ -0,0 +1,105 @@
+package models
+
+import "time"
+
+// OAuth 2.0 grant types supported by /oauth/token.
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+// OAuthClient is an application registered to obtain tokens from /oauth/token.
+// Public clients, such as single-page apps, have no secret and must use PKCE.
+// Client credentials tokens act as ServiceUserID.
+type OAuthClient struct {
+	ID            int       `json:"-"`
+	ClientID      string    `json:"client_id"`
+	SecretHash    string    `json:"-"`
+	Name          string    `json:"name"`
+	RedirectURIs  []string  `json:"redirect_uris"`
+	GrantTypes    []string  `json:"grant_types"`
+	Scopes        []string  `json:"scopes"`
+	ServiceUserID *int      `json:"service_user_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Confidential reports whether the client authenticates with a secret.
+func (c OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// CreateOAuthClientRequest is the body of a request to register an OAuth client.
+type CreateOAuthClientRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	RedirectURIs  []string `json:"redirect_uris" validate:"dive,url"`
+	GrantTypes    []string `json:"grant_types" validate:"required,min=1,dive,oneof=authorization_code refresh_token client_credentials"`
+	Scopes        []string `json:"scopes" validate:"dive,oneof=users:read users:write"`
+	Confidential  bool     `json:"confidential"`
+	ServiceUserID *int     `json:"service_user_id"`
+}
+
+// CreateOAuthClientResponse includes the client secret, which is shown only once.
+type CreateOAuthClientResponse struct {
+	OAuthClient
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// AuthorizationCode is a single-use code issued by /oauth/authorize and bound
+// to a PKCE code challenge. Only the hash of the code is stored.
+type AuthorizationCode struct {
+	ID          int
+	CodeHash    string
+	ClientID    string
+	UserID      int
+	RedirectURI string
+	// Whether the authorization request named the redirect URI, in which case
+	// the token request must repeat it
+	RedirectURIProvided bool
+	Scope               string
+	CodeChallenge       string // S256 challenge
+	ExpiresAt           time.Time
+	UsedAt              *time.Time
+	CreatedAt           time.Time
+}
+
+// OAuthConsentPrompt asks the user to approve an authorization request, which
+// is repeated as a POST with consent=approve or consent=deny.
+type OAuthConsentPrompt struct {
+	ClientID   string `json:"client_id"`
+	ClientName string `json:"client_name"`
+	Scope      string `json:"scope"`
+	Message    string `json:"message"`
+}
+
+// OAuthRefreshToken is a refresh token issued to an OAuth client on behalf of a user.
+type OAuthRefreshToken struct {
+	ID        int
+	TokenHash string
+	ClientID  string
+	UserID    int
+	Scope     string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// OAuthTokenResponse is the successful response of /oauth/token (RFC 6749 section 5.1).
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// IntrospectionResponse is the response of /oauth/introspect (RFC 7662 section 2.2).
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// OIDCLoginState is a pending login with an external OpenID Connect provider.
+// It is consumed by the callback, so every state can be used once.
+type OIDCLoginState struct {
+	ID           int
+	StateHash    string
+	Provider     string
+	Nonce        string // Must be echoed in the ID token
+	CodeVerifier string // PKCE verifier sent with the code exchange
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// UserIdentity links a user to the subject identifier of an external provider.
+type UserIdentity struct {
+	ID        int
+	UserID    int
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+// OIDCPendingLink is an external identity with the email of an existing local
+// account. It is linked once the account owner confirms with the password.
+type OIDCPendingLink struct {
+	ID        int
+	TokenHash string
+	UserID    int
+	Provider  string
+	Subject   string
+	Email     string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
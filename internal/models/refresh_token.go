@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// RefreshToken represents a persisted refresh token. Only the hash of the
+// opaque token handed to the client is stored.
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	FamilyID  string // Shared by every token produced by rotating the same login
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// TokenResponse is returned by /login and /token/refresh.
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // Access token lifetime in seconds
+}
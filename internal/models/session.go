@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Session is a login on one device. It spans the refresh token family
+// started by the login, and the access tokens issued from it carry its ID.
+type Session struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	FamilyID   string     `json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Current    bool       `json:"current"` // Whether the request was authenticated by this session
+}
@@ -6,3 +6,12 @@ type UpdateUserRequest struct {
 	Email        *string `json:"email"`    // Optional: Email field
 	PasswordHash *string `json:"password"` // Optional: Password field
 }
+
+// ReplaceUserRequest represents a full replacement of a user's profile. Fields
+// missing from the request are cleared, so they fail validation rather than
+// keeping their current values. The password is kept when it is omitted.
+type ReplaceUserRequest struct {
+	Name         string  `json:"name" validate:"required,min=2,max=20"`
+	Email        string  `json:"email" validate:"required,email"`
+	PasswordHash *string `json:"password" validate:"omitempty,min=6"`
+}
@@ -1,12 +1,27 @@
 package models
 
-import "github.com/go-playground/validator/v10"
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Roles that can be assigned to a user.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
 
 type User struct {
-	ID           int    `json:"id"`
-	Name         string `json:"name" validate:"required,min=2,max=20"`
-	Email        string `json:"email" validate:"required,email"`
-	PasswordHash string `json:"passwordHash" validate:"required,min=6"`
+	ID            int        `json:"id"`
+	Name          string     `json:"name" validate:"required,min=2,max=20"`
+	Email         string     `json:"email" validate:"required,email"`
+	PasswordHash  string     `json:"passwordHash" validate:"required,min=6"`
+	Role          string     `json:"role" validate:"omitempty,oneof=admin user"`
+	EmailVerified bool       `json:"email_verified"`
+	Version       int        `json:"version"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
 }
 
 // Validate Global validator instance
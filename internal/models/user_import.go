@@ -0,0 +1,42 @@
+package models
+
+// Statuses of the rows of a user import.
+const (
+	ImportRowCreated = "created" // The user was created
+	ImportRowValid   = "valid"   // The row is valid; only reported by dry runs
+	ImportRowFailed  = "failed"  // The row was skipped, see the error
+)
+
+// UserImportRecord is one row of a bulk user import.
+type UserImportRecord struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// User returns the user to create for the record, with the plain-text
+// password in PasswordHash like a POST /users request.
+func (r UserImportRecord) User() User {
+	return User{Name: r.Name, Email: r.Email, PasswordHash: r.Password, Role: r.Role}
+}
+
+// UserImportResult reports the outcome of one row of an import. Line is the
+// row's line number in the input.
+type UserImportResult struct {
+	Line   int    `json:"line"`
+	Email  string `json:"email,omitempty"`
+	Status string `json:"status"`
+	ID     int    `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// UserImportReport is the result of a bulk user import.
+type UserImportReport struct {
+	DryRun    bool               `json:"dry_run"`
+	Rows      int                `json:"rows"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Results   []UserImportResult `json:"results"`
+	Aborted   string             `json:"aborted,omitempty"` // Why the import stopped before the end of the input
+}
@@ -0,0 +1,102 @@
+package models
+
+import "time"
+
+// Fields users can be sorted by.
+const (
+	SortByID        = "id"
+	SortByName      = "name"
+	SortByEmail     = "email"
+	SortByCreatedAt = "created_at"
+)
+
+// Limits on the number of users returned per page.
+const (
+	DefaultUserPageSize = 20
+	MaxUserPageSize     = 100
+)
+
+// UserFilter narrows down a listing of users.
+type UserFilter struct {
+	NamePrefix     string // Case-insensitive prefix of the name
+	EmailDomain    string // Case-insensitive domain of the email address, e.g. "example.com"
+	IncludeDeleted bool   // Include users that were deleted but not purged yet
+}
+
+// ListUsersQuery is the query string of GET /users. Pages are selected either
+// with the opaque Cursor of the previous page, or with Offset.
+type ListUsersQuery struct {
+	UserFilter
+	Sort         string `validate:"omitempty,oneof=id name email created_at"`
+	Order        string `validate:"omitempty,oneof=asc desc"`
+	Limit        int    `validate:"min=0,max=100"`
+	Offset       int    `validate:"min=0"`
+	Cursor       string
+	IncludeTotal bool
+}
+
+// UserCursor is the position after the last user of a page in keyset
+// pagination: the value of the sort field and the ID as a tie-breaker.
+type UserCursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Value      string `json:"v"`
+	ID         int    `json:"id"`
+}
+
+// UserListParams are the validated parameters passed to the repository.
+type UserListParams struct {
+	UserFilter
+	Sort       string
+	Descending bool
+	Limit      int
+	Offset     int
+	After      *UserCursor // Keyset position; Offset is ignored when set
+}
+
+// UserPage is one page of users.
+type UserPage struct {
+	Users      []User
+	NextCursor string // Empty on the last page
+	Total      *int   // Only counted when requested
+}
+
+// Limits on the number of search results.
+const (
+	DefaultUserSearchLimit = 20
+	MaxUserSearchLimit     = 100
+)
+
+// UserSearchResult is a user matching a search, with its relevance and the
+// matched parts of the name and email highlighted.
+type UserSearchResult struct {
+	User
+	Rank      float64       `json:"rank"`
+	Highlight UserHighlight `json:"highlight"`
+}
+
+// UserHighlight holds the HTML-escaped name and email with the parts matching
+// the search terms wrapped in <mark> tags.
+type UserHighlight struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// UserExportRecord is a user as written by GET /users/export. It has no
+// password field, so password hashes can't end up in an export.
+type UserExportRecord struct {
+	ID            int        `json:"id" parquet:"id"`
+	Name          string     `json:"name" parquet:"name"`
+	Email         string     `json:"email" parquet:"email"`
+	Role          string     `json:"role" parquet:"role"`
+	EmailVerified bool       `json:"email_verified" parquet:"email_verified"`
+	Version       int        `json:"version" parquet:"version"`
+	CreatedAt     time.Time  `json:"created_at" parquet:"created_at"`
+	DeletedAt     *time.Time `json:"deleted_at" parquet:"deleted_at,optional"`
+}
+
+// NewUserExportRecord returns the exported fields of the user.
+func NewUserExportRecord(user User) UserExportRecord {
+	return UserExportRecord{ID: user.ID, Name: user.Name, Email: user.Email, Role: user.Role, EmailVerified: user.EmailVerified,
+		Version: user.Version, CreatedAt: user.CreatedAt, DeletedAt: user.DeletedAt}
+}
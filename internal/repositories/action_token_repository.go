@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"go-crud/internal/models"
+	"time"
+)
+
+var ErrActionTokenNotFound = errors.New("action token not found")
+
+// ActionTokenRepositoryInterface defines the methods for persisting single-use action tokens.
+type ActionTokenRepositoryInterface interface {
+	CreateActionToken(token models.ActionToken) (int, error)
+	GetActionTokenByHash(purpose, tokenHash string) (models.ActionToken, error)
+	MarkActionTokenUsed(id int) (bool, error)
+	CreateRateLimitedActionToken(token models.ActionToken, interval time.Duration, hourlyCap int) (bool, error)
+	GetLatestActionTokenCreatedAt(userID int, purpose string) (time.Time, error)
+	InvalidateActionTokens(userID int, purpose string) error
+}
+
+type ActionTokenRepository struct {
+	DB *sql.DB
+}
+
+func NewActionTokenRepository(db *sql.DB) *ActionTokenRepository {
+	return &ActionTokenRepository{DB: db}
+}
+
+const insertActionTokenQuery = "INSERT INTO action_tokens (user_id, purpose, token_hash, email, expires_at) VALUES ($1, $2, $3, NULLIF($4, ''), $5) RETURNING id"
+
+func (r *ActionTokenRepository) CreateActionToken(token models.ActionToken) (int, error) {
+	var id int
+	err := r.DB.QueryRow(insertActionTokenQuery, token.UserID, token.Purpose, token.TokenHash, token.Email, token.ExpiresAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// CreateRateLimitedActionToken creates the token unless the user got a token
+// for the same purpose within the interval, or hourlyCap of them within the
+// last hour. It reports whether the token was created. The user row is locked
+// while checking, so concurrent requests for one user are counted one by one.
+func (r *ActionTokenRepository) CreateRateLimitedActionToken(token models.ActionToken, interval time.Duration, hourlyCap int) (bool, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	if err := tx.QueryRow("SELECT id FROM users WHERE id = $1 FOR UPDATE", token.UserID).Scan(&userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrUserNotFound
+		}
+		return false, err
+	}
+
+	now := time.Now()
+	var last sql.NullTime
+	var count int
+	err = tx.QueryRow(`
+       SELECT MAX(created_at), COUNT(*) FILTER (WHERE created_at >= $3)
+       FROM action_tokens
+       WHERE user_id = $1 AND purpose = $2
+   `, token.UserID, token.Purpose, now.Add(-time.Hour)).Scan(&last, &count)
+	if err != nil {
+		return false, err
+	}
+	if (last.Valid && now.Sub(last.Time) < interval) || count >= hourlyCap {
+		return false, nil
+	}
+
+	var id int
+	if err := tx.QueryRow(insertActionTokenQuery, token.UserID, token.Purpose, token.TokenHash, token.Email, token.ExpiresAt).Scan(&id); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *ActionTokenRepository) GetActionTokenByHash(purpose, tokenHash string) (models.ActionToken, error) {
+	var token models.ActionToken
+	err := r.DB.QueryRow(`
+       SELECT id, user_id, purpose, token_hash, COALESCE(email, ''), expires_at, used_at, created_at
+       FROM action_tokens
+       WHERE purpose = $1 AND token_hash = $2
+   `, purpose, tokenHash).Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash, &token.Email,
+		&token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ActionToken{}, ErrActionTokenNotFound
+		}
+		return models.ActionToken{}, err
+	}
+	return token, nil
+}
+
+// MarkActionTokenUsed consumes the token. It reports false when the token had
+// already been used, so a token can never be redeemed twice.
+func (r *ActionTokenRepository) MarkActionTokenUsed(id int) (bool, error) {
+	result, err := r.DB.Exec("UPDATE action_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// GetLatestActionTokenCreatedAt returns the zero time when the user has no token for the purpose.
+func (r *ActionTokenRepository) GetLatestActionTokenCreatedAt(userID int, purpose string) (time.Time, error) {
+	var createdAt sql.NullTime
+	err := r.DB.QueryRow("SELECT MAX(created_at) FROM action_tokens WHERE user_id = $1 AND purpose = $2", userID, purpose).Scan(&createdAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return createdAt.Time, nil
+}
+
+// InvalidateActionTokens consumes every unused token of the user for the purpose.
+func (r *ActionTokenRepository) InvalidateActionTokens(userID int, purpose string) error {
+	_, err := r.DB.Exec("UPDATE action_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL",
+		userID, purpose)
+	return err
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"go-crud/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// APIKeyRepositoryInterface defines the methods for persisting API keys.
+type APIKeyRepositoryInterface interface {
+	CreateAPIKey(key models.APIKey) (models.APIKey, error)
+	GetAPIKeysByUser(userID int) ([]models.APIKey, error)
+	GetAPIKey(userID, id int) (models.APIKey, error)
+	GetAPIKeyByPrefix(prefix string) (models.APIKey, error)
+	UpdateAPIKey(key models.APIKey) error
+	DeleteAPIKey(userID, id int) error
+	TouchAPIKey(id int) error
+}
+
+type APIKeyRepository struct {
+	DB *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{DB: db}
+}
+
+const apiKeyColumns = "id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at"
+
+func scanAPIKey(row interface{ Scan(...any) error }) (models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, pq.Array(&key.Scopes),
+		&key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt)
+	return key, err
+}
+
+func (r *APIKeyRepository) CreateAPIKey(key models.APIKey) (models.APIKey, error) {
+	row := r.DB.QueryRow(
+		"INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING "+apiKeyColumns,
+		key.UserID, key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes), key.ExpiresAt,
+	)
+	return scanAPIKey(row)
+}
+
+func (r *APIKeyRepository) GetAPIKeysByUser(userID int) ([]models.APIKey, error) {
+	rows, err := r.DB.Query("SELECT "+apiKeyColumns+" FROM api_keys WHERE user_id = $1 ORDER BY id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// GetAPIKey returns the key only if it belongs to the user.
+func (r *APIKeyRepository) GetAPIKey(userID, id int) (models.APIKey, error) {
+	key, err := scanAPIKey(r.DB.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE id = $1 AND user_id = $2", id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, ErrAPIKeyNotFound
+		}
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepository) GetAPIKeyByPrefix(prefix string) (models.APIKey, error) {
+	key, err := scanAPIKey(r.DB.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE prefix = $1", prefix))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.APIKey{}, ErrAPIKeyNotFound
+		}
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+// UpdateAPIKey updates the name and scopes of the key.
+func (r *APIKeyRepository) UpdateAPIKey(key models.APIKey) error {
+	result, err := r.DB.Exec("UPDATE api_keys SET name = $1, scopes = $2 WHERE id = $3 AND user_id = $4",
+		key.Name, pq.Array(key.Scopes), key.ID, key.UserID)
+	if err != nil {
+		return err
+	}
+	return requireAPIKeyAffected(result)
+}
+
+func (r *APIKeyRepository) DeleteAPIKey(userID, id int) error {
+	result, err := r.DB.Exec("DELETE FROM api_keys WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	return requireAPIKeyAffected(result)
+}
+
+// TouchAPIKey records that the key was used. The timestamp is written at most
+// once a minute so busy keys don't cause a write on every request.
+func (r *APIKeyRepository) TouchAPIKey(id int) error {
+	_, err := r.DB.Exec(`
+       UPDATE api_keys SET last_used_at = NOW()
+       WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+   `, id)
+	return err
+}
+
+func requireAPIKeyAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"database/sql"
+	"go-crud/internal/models"
+)
+
+// ImpersonationRepositoryInterface defines the methods for the impersonation audit log.
+type ImpersonationRepositoryInterface interface {
+	CreateImpersonationSession(session models.ImpersonationSession) (int, error)
+	GetImpersonationSessions(userID *int) ([]models.ImpersonationSession, error)
+}
+
+type ImpersonationRepository struct {
+	DB *sql.DB
+}
+
+func NewImpersonationRepository(db *sql.DB) *ImpersonationRepository {
+	return &ImpersonationRepository{DB: db}
+}
+
+func (r *ImpersonationRepository) CreateImpersonationSession(session models.ImpersonationSession) (int, error) {
+	var id int
+	err := r.DB.QueryRow(`
+       INSERT INTO impersonation_sessions (actor_id, user_id, reason, token_id, ip_address, expires_at)
+       VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+   `, session.ActorID, session.UserID, session.Reason, session.TokenID, session.IPAddress, session.ExpiresAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetImpersonationSessions returns the sessions, newest first, optionally only
+// those impersonating the given user.
+func (r *ImpersonationRepository) GetImpersonationSessions(userID *int) ([]models.ImpersonationSession, error) {
+	rows, err := r.DB.Query(`
+       SELECT id, actor_id, user_id, reason, token_id, ip_address, expires_at, created_at
+       FROM impersonation_sessions
+       WHERE $1::INTEGER IS NULL OR user_id = $1
+       ORDER BY created_at DESC, id DESC
+   `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.ImpersonationSession{}
+	for rows.Next() {
+		var session models.ImpersonationSession
+		if err := rows.Scan(&session.ID, &session.ActorID, &session.UserID, &session.Reason, &session.TokenID,
+			&session.IPAddress, &session.ExpiresAt, &session.CreatedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"go-crud/internal/models"
+	"sync"
+	"time"
+)
+
+// LoginAttemptRepositoryInterface defines the methods for tracking failed logins.
+type LoginAttemptRepositoryInterface interface {
+	GetLoginAttempt(key string) (models.LoginAttempt, error)
+	ReserveLoginAttempt(key string, now time.Time, policy models.ThrottlePolicy) (models.LoginAttempt, bool, error)
+	ReleaseLoginAttempt(key string) error
+	DeleteLoginAttempt(key string) error
+}
+
+// LoginAttemptRepository stores failed logins in Postgres so that every
+// instance behind a load balancer sees the same counters.
+type LoginAttemptRepository struct {
+	DB *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{DB: db}
+}
+
+// GetLoginAttempt returns an attempt with zero failures when the key is unknown.
+func (r *LoginAttemptRepository) GetLoginAttempt(key string) (models.LoginAttempt, error) {
+	attempt := models.LoginAttempt{Key: key}
+	err := r.DB.QueryRow("SELECT failures, last_failure_at FROM login_attempts WHERE key = $1", key).
+		Scan(&attempt.Failures, &attempt.LastFailureAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return models.LoginAttempt{}, err
+	}
+	return attempt, nil
+}
+
+// ReserveLoginAttempt counts an attempt against the key unless the policy
+// currently blocks it, restarting the counter when the previous failure is
+// older than the policy's ResetAfter. The check and the increment happen in
+// one statement, so concurrent attempts can't all pass before any is counted.
+// When the attempt is blocked, the unchanged counter is returned with false.
+func (r *LoginAttemptRepository) ReserveLoginAttempt(key string, now time.Time, policy models.ThrottlePolicy) (models.LoginAttempt, bool, error) {
+	query := `
+       INSERT INTO login_attempts (key, failures, last_failure_at)
+       VALUES ($1, 1, $2)
+       ON CONFLICT (key) DO UPDATE SET
+           failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+           last_failure_at = EXCLUDED.last_failure_at
+       WHERE login_attempts.last_failure_at < $3
+           OR login_attempts.failures < $4
+           OR login_attempts.last_failure_at + CASE
+                  WHEN login_attempts.failures >= $5 THEN $7::float8
+                  ELSE LEAST($6::float8 * power(2, login_attempts.failures - $4), $7::float8)
+              END * INTERVAL '1 microsecond' <= $2
+       RETURNING failures, last_failure_at
+   `
+	attempt := models.LoginAttempt{Key: key}
+	err := r.DB.QueryRow(query, key, now, now.Add(-policy.ResetAfter), policy.FreeAttempts, policy.MaxFailures,
+		policy.BaseDelay.Microseconds(), policy.LockoutDuration.Microseconds()).
+		Scan(&attempt.Failures, &attempt.LastFailureAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		attempt, err = r.GetLoginAttempt(key)
+		return attempt, false, err
+	}
+	if err != nil {
+		return models.LoginAttempt{}, false, err
+	}
+	return attempt, true, nil
+}
+
+// ReleaseLoginAttempt takes back one reserved attempt after it succeeded.
+func (r *LoginAttemptRepository) ReleaseLoginAttempt(key string) error {
+	_, err := r.DB.Exec("UPDATE login_attempts SET failures = failures - 1 WHERE key = $1 AND failures > 0", key)
+	return err
+}
+
+func (r *LoginAttemptRepository) DeleteLoginAttempt(key string) error {
+	_, err := r.DB.Exec("DELETE FROM login_attempts WHERE key = $1", key)
+	return err
+}
+
+// maxInMemoryLoginAttempts caps the number of keys kept in memory. When full,
+// stale entries are swept and, failing that, the least recently failed key is
+// evicted.
+const maxInMemoryLoginAttempts = 10000
+
+// InMemoryLoginAttemptRepository keeps failed logins in process memory. It is
+// suitable for a single instance; counters are lost on restart.
+type InMemoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+}
+
+func NewInMemoryLoginAttemptRepository() *InMemoryLoginAttemptRepository {
+	return &InMemoryLoginAttemptRepository{attempts: make(map[string]models.LoginAttempt)}
+}
+
+func (r *InMemoryLoginAttemptRepository) GetLoginAttempt(key string) (models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return models.LoginAttempt{Key: key}, nil
+	}
+	return attempt, nil
+}
+
+func (r *InMemoryLoginAttemptRepository) ReserveLoginAttempt(key string, now time.Time, policy models.ThrottlePolicy) (models.LoginAttempt, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	resetBefore := now.Add(-policy.ResetAfter)
+	attempt, ok := r.attempts[key]
+	if ok && policy.Wait(attempt, now) > 0 {
+		return attempt, false, nil
+	}
+	if !ok {
+		r.makeRoom(resetBefore)
+	}
+
+	if !ok || attempt.LastFailureAt.Before(resetBefore) {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	r.attempts[key] = attempt
+	return attempt, true, nil
+}
+
+// makeRoom keeps the map below maxInMemoryLoginAttempts before a new key is
+// added. Callers must hold r.mu.
+func (r *InMemoryLoginAttemptRepository) makeRoom(resetBefore time.Time) {
+	if len(r.attempts) < maxInMemoryLoginAttempts {
+		return
+	}
+	for k, attempt := range r.attempts {
+		if attempt.LastFailureAt.Before(resetBefore) {
+			delete(r.attempts, k)
+		}
+	}
+
+	for len(r.attempts) >= maxInMemoryLoginAttempts {
+		oldestKey := ""
+		var oldest time.Time
+		for k, attempt := range r.attempts {
+			if oldestKey == "" || attempt.LastFailureAt.Before(oldest) {
+				oldestKey, oldest = k, attempt.LastFailureAt
+			}
+		}
+		delete(r.attempts, oldestKey)
+	}
+}
+
+func (r *InMemoryLoginAttemptRepository) ReleaseLoginAttempt(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attempt, ok := r.attempts[key]; ok && attempt.Failures > 0 {
+		attempt.Failures--
+		r.attempts[key] = attempt
+	}
+	return nil
+}
+
+func (r *InMemoryLoginAttemptRepository) DeleteLoginAttempt(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
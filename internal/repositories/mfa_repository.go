@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"go-crud/internal/models"
+)
+
+var ErrTOTPNotFound = errors.New("totp not enrolled")
+
+// MFARepositoryInterface defines the methods for persisting TOTP enrollments and recovery codes.
+type MFARepositoryInterface interface {
+	GetTOTP(userID int) (models.TOTPSettings, error)
+	SaveTOTPSecret(userID int, secret string) error
+	EnableTOTP(userID int, step int64) error
+	UpdateTOTPLastUsedStep(userID int, step int64) (bool, error)
+	DeleteTOTP(userID int) error
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	GetUnusedRecoveryCodes(userID int) ([]models.RecoveryCode, error)
+	MarkRecoveryCodeUsed(id int) (bool, error)
+}
+
+type MFARepository struct {
+	DB *sql.DB
+}
+
+func NewMFARepository(db *sql.DB) *MFARepository {
+	return &MFARepository{DB: db}
+}
+
+func (r *MFARepository) GetTOTP(userID int) (models.TOTPSettings, error) {
+	var settings models.TOTPSettings
+	err := r.DB.QueryRow("SELECT user_id, secret, enabled, last_used_step FROM user_totp WHERE user_id = $1", userID).
+		Scan(&settings.UserID, &settings.Secret, &settings.Enabled, &settings.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TOTPSettings{}, ErrTOTPNotFound
+		}
+		return models.TOTPSettings{}, err
+	}
+	return settings, nil
+}
+
+// SaveTOTPSecret stores a pending (not yet enabled) secret, replacing any previous pending one.
+func (r *MFARepository) SaveTOTPSecret(userID int, secret string) error {
+	query := `
+       INSERT INTO user_totp (user_id, secret)
+       VALUES ($1, $2)
+       ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, enabled = FALSE, last_used_step = 0
+       WHERE user_totp.enabled = FALSE
+   `
+	_, err := r.DB.Exec(query, userID, secret)
+	return err
+}
+
+func (r *MFARepository) EnableTOTP(userID int, step int64) error {
+	_, err := r.DB.Exec("UPDATE user_totp SET enabled = TRUE, last_used_step = $2 WHERE user_id = $1", userID, step)
+	return err
+}
+
+// UpdateTOTPLastUsedStep records an accepted code. It reports false when a code
+// for the same or a later step was already accepted.
+func (r *MFARepository) UpdateTOTPLastUsedStep(userID int, step int64) (bool, error) {
+	result, err := r.DB.Exec("UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2", userID, step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// DeleteTOTP removes the enrollment together with its recovery codes.
+func (r *MFARepository) DeleteTOTP(userID int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_totp WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *MFARepository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM mfa_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		if _, err := tx.Exec("INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, codeHash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *MFARepository) GetUnusedRecoveryCodes(userID int) ([]models.RecoveryCode, error) {
+	rows, err := r.DB.Query("SELECT id, user_id, code_hash FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []models.RecoveryCode
+	for rows.Next() {
+		var code models.RecoveryCode
+		if err := rows.Scan(&code.ID, &code.UserID, &code.CodeHash); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, rows.Err()
+}
+
+func (r *MFARepository) MarkRecoveryCodeUsed(id int) (bool, error) {
+	result, err := r.DB.Exec("UPDATE mfa_recovery_codes SET used_at = NOW() WHERE id = $1 AND used_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
@@ -7,6 +7,7 @@ package repositories
 import (
 	models "go-crud/internal/models"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,6 +35,21 @@ func (m *MockUserRepositoryInterface) EXPECT() *MockUserRepositoryInterfaceMockR
 	return m.recorder
 }
 
+// CountUsers mocks base method.
+func (m *MockUserRepositoryInterface) CountUsers(filter models.UserFilter) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsers", filter)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsers indicates an expected call of CountUsers.
+func (mr *MockUserRepositoryInterfaceMockRecorder) CountUsers(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsers", reflect.TypeOf((*MockUserRepositoryInterface)(nil).CountUsers), filter)
+}
+
 // CreateUser mocks base method.
 func (m *MockUserRepositoryInterface) CreateUser(user models.User) (int, error) {
 	m.ctrl.T.Helper()
@@ -50,17 +66,46 @@ func (mr *MockUserRepositoryInterfaceMockRecorder) CreateUser(user interface{})
 }
 
 // DeleteUser mocks base method.
-func (m *MockUserRepositoryInterface) DeleteUser(id int) error {
+func (m *MockUserRepositoryInterface) DeleteUser(id, version int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUser", id)
+	ret := m.ctrl.Call(m, "DeleteUser", id, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteUser indicates an expected call of DeleteUser.
-func (mr *MockUserRepositoryInterfaceMockRecorder) DeleteUser(id interface{}) *gomock.Call {
+func (mr *MockUserRepositoryInterfaceMockRecorder) DeleteUser(id, version interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserRepositoryInterface)(nil).DeleteUser), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserRepositoryInterface)(nil).DeleteUser), id, version)
+}
+
+// ExistingEmails mocks base method.
+func (m *MockUserRepositoryInterface) ExistingEmails(emails []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExistingEmails", emails)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExistingEmails indicates an expected call of ExistingEmails.
+func (mr *MockUserRepositoryInterfaceMockRecorder) ExistingEmails(emails interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExistingEmails", reflect.TypeOf((*MockUserRepositoryInterface)(nil).ExistingEmails), emails)
+}
+
+// ExportUsers mocks base method.
+func (m *MockUserRepositoryInterface) ExportUsers(filter models.UserFilter, each func(models.User) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExportUsers", filter, each)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExportUsers indicates an expected call of ExportUsers.
+func (mr *MockUserRepositoryInterfaceMockRecorder) ExportUsers(filter, each interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportUsers", reflect.TypeOf((*MockUserRepositoryInterface)(nil).ExportUsers), filter, each)
 }
 
 // GetAllUsers mocks base method.
@@ -78,6 +123,21 @@ func (mr *MockUserRepositoryInterfaceMockRecorder) GetAllUsers() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllUsers", reflect.TypeOf((*MockUserRepositoryInterface)(nil).GetAllUsers))
 }
 
+// GetPasswordHash mocks base method.
+func (m *MockUserRepositoryInterface) GetPasswordHash(id int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasswordHash", id)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasswordHash indicates an expected call of GetPasswordHash.
+func (mr *MockUserRepositoryInterfaceMockRecorder) GetPasswordHash(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordHash", reflect.TypeOf((*MockUserRepositoryInterface)(nil).GetPasswordHash), id)
+}
+
 // GetUserByEmail mocks base method.
 func (m *MockUserRepositoryInterface) GetUserByEmail(email string) (models.User, error) {
 	m.ctrl.T.Helper()
@@ -108,6 +168,137 @@ func (mr *MockUserRepositoryInterfaceMockRecorder) GetUserByID(id interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserRepositoryInterface)(nil).GetUserByID), id)
 }
 
+// GetUserByIDIncludingDeleted mocks base method.
+func (m *MockUserRepositoryInterface) GetUserByIDIncludingDeleted(id int) (models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByIDIncludingDeleted", id)
+	ret0, _ := ret[0].(models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByIDIncludingDeleted indicates an expected call of GetUserByIDIncludingDeleted.
+func (mr *MockUserRepositoryInterfaceMockRecorder) GetUserByIDIncludingDeleted(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByIDIncludingDeleted", reflect.TypeOf((*MockUserRepositoryInterface)(nil).GetUserByIDIncludingDeleted), id)
+}
+
+// ImportUsers mocks base method.
+func (m *MockUserRepositoryInterface) ImportUsers(users []models.User) (map[string]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportUsers", users)
+	ret0, _ := ret[0].(map[string]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportUsers indicates an expected call of ImportUsers.
+func (mr *MockUserRepositoryInterfaceMockRecorder) ImportUsers(users interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportUsers", reflect.TypeOf((*MockUserRepositoryInterface)(nil).ImportUsers), users)
+}
+
+// ListUsers mocks base method.
+func (m *MockUserRepositoryInterface) ListUsers(params models.UserListParams) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", params)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockUserRepositoryInterfaceMockRecorder) ListUsers(params interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockUserRepositoryInterface)(nil).ListUsers), params)
+}
+
+// MarkEmailVerified mocks base method.
+func (m *MockUserRepositoryInterface) MarkEmailVerified(id int, email string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkEmailVerified", id, email)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkEmailVerified indicates an expected call of MarkEmailVerified.
+func (mr *MockUserRepositoryInterfaceMockRecorder) MarkEmailVerified(id, email interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkEmailVerified", reflect.TypeOf((*MockUserRepositoryInterface)(nil).MarkEmailVerified), id, email)
+}
+
+// PurgeDeletedUsers mocks base method.
+func (m *MockUserRepositoryInterface) PurgeDeletedUsers(deletedBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedUsers", deletedBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedUsers indicates an expected call of PurgeDeletedUsers.
+func (mr *MockUserRepositoryInterfaceMockRecorder) PurgeDeletedUsers(deletedBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedUsers", reflect.TypeOf((*MockUserRepositoryInterface)(nil).PurgeDeletedUsers), deletedBefore)
+}
+
+// RestoreUser mocks base method.
+func (m *MockUserRepositoryInterface) RestoreUser(id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUser", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreUser indicates an expected call of RestoreUser.
+func (mr *MockUserRepositoryInterfaceMockRecorder) RestoreUser(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUser", reflect.TypeOf((*MockUserRepositoryInterface)(nil).RestoreUser), id)
+}
+
+// Search mocks base method.
+func (m *MockUserRepositoryInterface) Search(query string, limit int) ([]models.UserSearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", query, limit)
+	ret0, _ := ret[0].([]models.UserSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockUserRepositoryInterfaceMockRecorder) Search(query, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockUserRepositoryInterface)(nil).Search), query, limit)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockUserRepositoryInterface) UpdatePassword(id int, passwordHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", id, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockUserRepositoryInterfaceMockRecorder) UpdatePassword(id, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockUserRepositoryInterface)(nil).UpdatePassword), id, passwordHash)
+}
+
+// UpdatePasswordIfUnchanged mocks base method.
+func (m *MockUserRepositoryInterface) UpdatePasswordIfUnchanged(id int, oldHash, newHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordIfUnchanged", id, oldHash, newHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordIfUnchanged indicates an expected call of UpdatePasswordIfUnchanged.
+func (mr *MockUserRepositoryInterfaceMockRecorder) UpdatePasswordIfUnchanged(id, oldHash, newHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordIfUnchanged", reflect.TypeOf((*MockUserRepositoryInterface)(nil).UpdatePasswordIfUnchanged), id, oldHash, newHash)
+}
+
 // UpdateUser mocks base method.
 func (m *MockUserRepositoryInterface) UpdateUser(id int, user models.User) error {
 	m.ctrl.T.Helper()
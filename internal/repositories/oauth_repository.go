@@ -0,0 +1,216 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"go-crud/internal/models"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrOAuthClientNotFound       = errors.New("OAuth client not found")
+	ErrAuthorizationCodeNotFound = errors.New("authorization code not found")
+	ErrOAuthRefreshTokenNotFound = errors.New("OAuth refresh token not found")
+	ErrOAuthConsentNotFound      = errors.New("OAuth consent not found")
+)
+
+// OAuthRepositoryInterface defines the methods for persisting OAuth clients,
+// authorization codes and OAuth refresh tokens.
+type OAuthRepositoryInterface interface {
+	CreateClient(client models.OAuthClient) (models.OAuthClient, error)
+	GetClient(clientID string) (models.OAuthClient, error)
+	GetClients() ([]models.OAuthClient, error)
+	DeleteClient(clientID string) error
+	CreateAuthorizationCode(code models.AuthorizationCode) error
+	ConsumeAuthorizationCode(codeHash string) (models.AuthorizationCode, error)
+	CreateOAuthRefreshToken(token models.OAuthRefreshToken) error
+	GetOAuthRefreshToken(tokenHash string) (models.OAuthRefreshToken, error)
+	RevokeOAuthRefreshToken(id int) (bool, error)
+	RevokeOAuthRefreshTokens(clientID string, userID int) error
+	RevokeUserOAuthRefreshTokens(userID int) error
+	GetConsent(userID int, clientID string) (string, error)
+	SaveConsent(userID int, clientID, scope string) error
+}
+
+type OAuthRepository struct {
+	DB *sql.DB
+}
+
+func NewOAuthRepository(db *sql.DB) *OAuthRepository {
+	return &OAuthRepository{DB: db}
+}
+
+const oauthClientColumns = "id, client_id, COALESCE(client_secret_hash, ''), name, redirect_uris, grant_types, scopes, service_user_id, created_at"
+
+func scanOAuthClient(row interface{ Scan(...any) error }) (models.OAuthClient, error) {
+	var client models.OAuthClient
+	var serviceUserID sql.NullInt64
+	err := row.Scan(&client.ID, &client.ClientID, &client.SecretHash, &client.Name, pq.Array(&client.RedirectURIs),
+		pq.Array(&client.GrantTypes), pq.Array(&client.Scopes), &serviceUserID, &client.CreatedAt)
+	if err != nil {
+		return models.OAuthClient{}, err
+	}
+	if serviceUserID.Valid {
+		id := int(serviceUserID.Int64)
+		client.ServiceUserID = &id
+	}
+	return client, nil
+}
+
+func (r *OAuthRepository) CreateClient(client models.OAuthClient) (models.OAuthClient, error) {
+	row := r.DB.QueryRow(`
+       INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris, grant_types, scopes, service_user_id)
+       VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7)
+       RETURNING `+oauthClientColumns,
+		client.ClientID, client.SecretHash, client.Name, pq.Array(client.RedirectURIs),
+		pq.Array(client.GrantTypes), pq.Array(client.Scopes), client.ServiceUserID,
+	)
+	return scanOAuthClient(row)
+}
+
+func (r *OAuthRepository) GetClient(clientID string) (models.OAuthClient, error) {
+	client, err := scanOAuthClient(r.DB.QueryRow("SELECT "+oauthClientColumns+" FROM oauth_clients WHERE client_id = $1", clientID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OAuthClient{}, ErrOAuthClientNotFound
+		}
+		return models.OAuthClient{}, err
+	}
+	return client, nil
+}
+
+func (r *OAuthRepository) GetClients() ([]models.OAuthClient, error) {
+	rows, err := r.DB.Query("SELECT " + oauthClientColumns + " FROM oauth_clients ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []models.OAuthClient{}
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, rows.Err()
+}
+
+// DeleteClient deletes the client with its outstanding codes and refresh tokens.
+func (r *OAuthRepository) DeleteClient(clientID string) error {
+	result, err := r.DB.Exec("DELETE FROM oauth_clients WHERE client_id = $1", clientID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+func (r *OAuthRepository) CreateAuthorizationCode(code models.AuthorizationCode) error {
+	_, err := r.DB.Exec(`
+       INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, redirect_uri_provided, scope, code_challenge, expires_at)
+       VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+   `, code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, code.RedirectURIProvided, code.Scope, code.CodeChallenge, code.ExpiresAt)
+	return err
+}
+
+// ConsumeAuthorizationCode marks the code used and returns it. A code that
+// doesn't exist or was already used is reported as not found, so a code can
+// never be redeemed twice.
+func (r *OAuthRepository) ConsumeAuthorizationCode(codeHash string) (models.AuthorizationCode, error) {
+	var code models.AuthorizationCode
+	err := r.DB.QueryRow(`
+       UPDATE oauth_authorization_codes SET used_at = NOW()
+       WHERE code_hash = $1 AND used_at IS NULL
+       RETURNING id, code_hash, client_id, user_id, redirect_uri, redirect_uri_provided, scope, code_challenge, expires_at, used_at, created_at
+   `, codeHash).Scan(&code.ID, &code.CodeHash, &code.ClientID, &code.UserID, &code.RedirectURI, &code.RedirectURIProvided, &code.Scope,
+		&code.CodeChallenge, &code.ExpiresAt, &code.UsedAt, &code.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.AuthorizationCode{}, ErrAuthorizationCodeNotFound
+		}
+		return models.AuthorizationCode{}, err
+	}
+	return code, nil
+}
+
+func (r *OAuthRepository) CreateOAuthRefreshToken(token models.OAuthRefreshToken) error {
+	_, err := r.DB.Exec(`
+       INSERT INTO oauth_refresh_tokens (token_hash, client_id, user_id, scope, expires_at)
+       VALUES ($1, $2, $3, $4, $5)
+   `, token.TokenHash, token.ClientID, token.UserID, token.Scope, token.ExpiresAt)
+	return err
+}
+
+func (r *OAuthRepository) GetOAuthRefreshToken(tokenHash string) (models.OAuthRefreshToken, error) {
+	var token models.OAuthRefreshToken
+	err := r.DB.QueryRow(`
+       SELECT id, token_hash, client_id, user_id, scope, expires_at, revoked_at, created_at
+       FROM oauth_refresh_tokens
+       WHERE token_hash = $1
+   `, tokenHash).Scan(&token.ID, &token.TokenHash, &token.ClientID, &token.UserID, &token.Scope,
+		&token.ExpiresAt, &token.RevokedAt, &token.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OAuthRefreshToken{}, ErrOAuthRefreshTokenNotFound
+		}
+		return models.OAuthRefreshToken{}, err
+	}
+	return token, nil
+}
+
+// RevokeOAuthRefreshToken reports false when the token was already revoked.
+func (r *OAuthRepository) RevokeOAuthRefreshToken(id int) (bool, error) {
+	result, err := r.DB.Exec("UPDATE oauth_refresh_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// RevokeOAuthRefreshTokens revokes every refresh token the client holds for the user.
+func (r *OAuthRepository) RevokeOAuthRefreshTokens(clientID string, userID int) error {
+	_, err := r.DB.Exec("UPDATE oauth_refresh_tokens SET revoked_at = NOW() WHERE client_id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		clientID, userID)
+	return err
+}
+
+// RevokeUserOAuthRefreshTokens revokes every refresh token any client holds for the user.
+func (r *OAuthRepository) RevokeUserOAuthRefreshTokens(userID int) error {
+	_, err := r.DB.Exec("UPDATE oauth_refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
+
+// GetConsent returns the scopes the user approved for the client.
+func (r *OAuthRepository) GetConsent(userID int, clientID string) (string, error) {
+	var scope string
+	err := r.DB.QueryRow("SELECT scope FROM oauth_consents WHERE user_id = $1 AND client_id = $2", userID, clientID).Scan(&scope)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrOAuthConsentNotFound
+		}
+		return "", err
+	}
+	return scope, nil
+}
+
+func (r *OAuthRepository) SaveConsent(userID int, clientID, scope string) error {
+	_, err := r.DB.Exec(`
+       INSERT INTO oauth_consents (user_id, client_id, scope)
+       VALUES ($1, $2, $3)
+       ON CONFLICT (user_id, client_id) DO UPDATE SET scope = EXCLUDED.scope, updated_at = NOW()
+   `, userID, clientID, scope)
+	return err
+}
@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"go-crud/internal/models"
+)
+
+var (
+	ErrOIDCLoginStateNotFound  = errors.New("OIDC login state not found")
+	ErrUserIdentityNotFound    = errors.New("user identity not found")
+	ErrOIDCPendingLinkNotFound = errors.New("pending OIDC link not found")
+	ErrUserIdentityExists      = errors.New("user identity is already linked")
+)
+
+// OIDCRepositoryInterface defines the methods for persisting pending OIDC
+// logins and links between users and external identities.
+type OIDCRepositoryInterface interface {
+	CreateLoginState(state models.OIDCLoginState) error
+	ConsumeLoginState(stateHash string) (models.OIDCLoginState, error)
+	GetIdentity(provider, subject string) (models.UserIdentity, error)
+	CreateIdentity(identity models.UserIdentity) error
+	CreatePendingLink(link models.OIDCPendingLink) error
+	ConsumePendingLink(tokenHash string) (models.OIDCPendingLink, error)
+}
+
+type OIDCRepository struct {
+	DB *sql.DB
+}
+
+func NewOIDCRepository(db *sql.DB) *OIDCRepository {
+	return &OIDCRepository{DB: db}
+}
+
+func (r *OIDCRepository) CreateLoginState(state models.OIDCLoginState) error {
+	_, err := r.DB.Exec(`
+       INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, expires_at)
+       VALUES ($1, $2, $3, $4, $5)
+   `, state.StateHash, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	return err
+}
+
+// ConsumeLoginState deletes the state and returns it, so that a state can
+// never be used twice. Expired states are cleaned up along the way.
+func (r *OIDCRepository) ConsumeLoginState(stateHash string) (models.OIDCLoginState, error) {
+	if _, err := r.DB.Exec("DELETE FROM oidc_login_states WHERE expires_at < NOW()"); err != nil {
+		return models.OIDCLoginState{}, err
+	}
+
+	var state models.OIDCLoginState
+	err := r.DB.QueryRow(`
+       DELETE FROM oidc_login_states WHERE state_hash = $1
+       RETURNING id, state_hash, provider, nonce, code_verifier, expires_at, created_at
+   `, stateHash).Scan(&state.ID, &state.StateHash, &state.Provider, &state.Nonce, &state.CodeVerifier,
+		&state.ExpiresAt, &state.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OIDCLoginState{}, ErrOIDCLoginStateNotFound
+		}
+		return models.OIDCLoginState{}, err
+	}
+	return state, nil
+}
+
+func (r *OIDCRepository) GetIdentity(provider, subject string) (models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.DB.QueryRow(`
+       SELECT id, user_id, provider, subject, email, created_at
+       FROM user_identities
+       WHERE provider = $1 AND subject = $2
+   `, provider, subject).Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+		&identity.Email, &identity.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.UserIdentity{}, ErrUserIdentityNotFound
+		}
+		return models.UserIdentity{}, err
+	}
+	return identity, nil
+}
+
+// CreateIdentity links the identity to its user. It fails with
+// ErrUserIdentityExists if the identity is already linked.
+func (r *OIDCRepository) CreateIdentity(identity models.UserIdentity) error {
+	result, err := r.DB.Exec(`
+       INSERT INTO user_identities (user_id, provider, subject, email)
+       VALUES ($1, $2, $3, $4)
+       ON CONFLICT (provider, subject) DO NOTHING
+   `, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserIdentityExists
+	}
+	return nil
+}
+
+func (r *OIDCRepository) CreatePendingLink(link models.OIDCPendingLink) error {
+	_, err := r.DB.Exec(`
+       INSERT INTO oidc_pending_links (token_hash, user_id, provider, subject, email, expires_at)
+       VALUES ($1, $2, $3, $4, $5, $6)
+   `, link.TokenHash, link.UserID, link.Provider, link.Subject, link.Email, link.ExpiresAt)
+	return err
+}
+
+// ConsumePendingLink deletes the pending link and returns it, like
+// ConsumeLoginState.
+func (r *OIDCRepository) ConsumePendingLink(tokenHash string) (models.OIDCPendingLink, error) {
+	if _, err := r.DB.Exec("DELETE FROM oidc_pending_links WHERE expires_at < NOW()"); err != nil {
+		return models.OIDCPendingLink{}, err
+	}
+
+	var link models.OIDCPendingLink
+	err := r.DB.QueryRow(`
+       DELETE FROM oidc_pending_links WHERE token_hash = $1
+       RETURNING id, token_hash, user_id, provider, subject, email, expires_at, created_at
+   `, tokenHash).Scan(&link.ID, &link.TokenHash, &link.UserID, &link.Provider, &link.Subject, &link.Email,
+		&link.ExpiresAt, &link.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.OIDCPendingLink{}, ErrOIDCPendingLinkNotFound
+		}
+		return models.OIDCPendingLink{}, err
+	}
+	return link, nil
+}
@@ -0,0 +1,63 @@
+package repositories
+
+import "database/sql"
+
+// PasswordHistoryRepositoryInterface defines the methods for persisting the
+// hashes of passwords a user had before.
+type PasswordHistoryRepositoryInterface interface {
+	AddPasswordHistory(userID int, passwordHash string, keep int) error
+	GetPasswordHistory(userID int, limit int) ([]string, error)
+}
+
+type PasswordHistoryRepository struct {
+	DB *sql.DB
+}
+
+func NewPasswordHistoryRepository(db *sql.DB) *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{DB: db}
+}
+
+// AddPasswordHistory records a previous password hash and deletes all but the
+// most recent keep entries of the user.
+func (r *PasswordHistoryRepository) AddPasswordHistory(userID int, passwordHash string, keep int) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT INTO password_history (user_id, password_hash) VALUES ($1, $2)", userID, passwordHash); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+       DELETE FROM password_history
+       WHERE user_id = $1 AND id NOT IN (
+           SELECT id FROM password_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2
+       )
+   `, userID, keep)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetPasswordHistory returns the most recent previous password hashes, newest first.
+func (r *PasswordHistoryRepository) GetPasswordHistory(userID int, limit int) ([]string, error) {
+	rows, err := r.DB.Query(
+		"SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2",
+		userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"go-crud/internal/models"
+)
+
+var ErrRefreshTokenNotFound = errors.New("refresh token not found")
+
+// RefreshTokenRepositoryInterface defines the methods for persisting refresh tokens.
+type RefreshTokenRepositoryInterface interface {
+	CreateRefreshToken(token models.RefreshToken) (int, error)
+	GetRefreshTokenByHash(tokenHash string) (models.RefreshToken, error)
+	MarkRefreshTokenUsed(id int) (bool, error)
+	RevokeRefreshTokenFamily(familyID string) error
+	RevokeUserRefreshTokens(userID int) error
+}
+
+type RefreshTokenRepository struct {
+	DB *sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{DB: db}
+}
+
+func (r *RefreshTokenRepository) CreateRefreshToken(token models.RefreshToken) (int, error) {
+	var id int
+	err := r.DB.QueryRow(
+		"INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		token.UserID, token.TokenHash, token.FamilyID, token.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *RefreshTokenRepository) GetRefreshTokenByHash(tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.DB.QueryRow(`
+       SELECT id, user_id, token_hash, family_id, expires_at, used_at, revoked_at, created_at
+       FROM refresh_tokens
+       WHERE token_hash = $1
+   `, tokenHash).Scan(&token.ID, &token.UserID, &token.TokenHash, &token.FamilyID,
+		&token.ExpiresAt, &token.UsedAt, &token.RevokedAt, &token.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, ErrRefreshTokenNotFound
+		}
+		return models.RefreshToken{}, err
+	}
+	return token, nil
+}
+
+// MarkRefreshTokenUsed marks the token as consumed. It reports false when the
+// token had already been used, which lets concurrent refreshes detect reuse.
+func (r *RefreshTokenRepository) MarkRefreshTokenUsed(id int) (bool, error) {
+	result, err := r.DB.Exec("UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (r *RefreshTokenRepository) RevokeRefreshTokenFamily(familyID string) error {
+	_, err := r.DB.Exec("UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL", familyID)
+	return err
+}
+
+func (r *RefreshTokenRepository) RevokeUserRefreshTokens(userID int) error {
+	_, err := r.DB.Exec("UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
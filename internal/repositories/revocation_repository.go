@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// RevocationRepositoryInterface defines the methods for persisting revoked access tokens.
+type RevocationRepositoryInterface interface {
+	RevokeToken(jti string, expiresAt time.Time) error
+	ConsumeToken(jti string, expiresAt time.Time) (bool, error)
+	IsTokenRevoked(jti string) (bool, error)
+	RevokeUserTokens(userID int, before time.Time) error
+	GetUserTokensRevokedBefore(userID int) (time.Time, error)
+	RevokeSession(sessionID int) error
+	IsSessionRevoked(sessionID int) (bool, error)
+}
+
+type RevocationRepository struct {
+	DB *sql.DB
+}
+
+func NewRevocationRepository(db *sql.DB) *RevocationRepository {
+	return &RevocationRepository{DB: db}
+}
+
+func (r *RevocationRepository) RevokeToken(jti string, expiresAt time.Time) error {
+	_, err := r.DB.Exec("INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING", jti, expiresAt)
+	return err
+}
+
+// ConsumeToken revokes the token and reports whether this call did so, which
+// lets single-use tokens be redeemed exactly once.
+func (r *RevocationRepository) ConsumeToken(jti string, expiresAt time.Time) (bool, error) {
+	result, err := r.DB.Exec("INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING", jti, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *RevocationRepository) IsTokenRevoked(jti string) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti).Scan(&exists)
+	return exists, err
+}
+
+// RevokeUserTokens invalidates every token issued to the user before the given time.
+func (r *RevocationRepository) RevokeUserTokens(userID int, before time.Time) error {
+	query := `
+       INSERT INTO user_token_revocations (user_id, revoked_before)
+       VALUES ($1, $2)
+       ON CONFLICT (user_id) DO UPDATE SET revoked_before = EXCLUDED.revoked_before
+   `
+	_, err := r.DB.Exec(query, userID, before)
+	return err
+}
+
+// GetUserTokensRevokedBefore returns the zero time when the user has never revoked all tokens.
+func (r *RevocationRepository) GetUserTokensRevokedBefore(userID int) (time.Time, error) {
+	var before time.Time
+	err := r.DB.QueryRow("SELECT revoked_before FROM user_token_revocations WHERE user_id = $1", userID).Scan(&before)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return before, nil
+}
+
+// RevokeSession invalidates every token issued for the session.
+func (r *RevocationRepository) RevokeSession(sessionID int) error {
+	_, err := r.DB.Exec("UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", sessionID)
+	return err
+}
+
+// IsSessionRevoked reports whether the session was revoked. Sessions that no
+// longer exist count as revoked.
+func (r *RevocationRepository) IsSessionRevoked(sessionID int) (bool, error) {
+	var revoked bool
+	err := r.DB.QueryRow("SELECT revoked_at IS NOT NULL FROM sessions WHERE id = $1", sessionID).Scan(&revoked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return true, nil
+		}
+		return false, err
+	}
+	return revoked, nil
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"go-crud/internal/models"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionRepositoryInterface defines the methods for persisting login sessions.
+type SessionRepositoryInterface interface {
+	CreateSession(session models.Session) (int, error)
+	GetSession(id int) (models.Session, error)
+	GetActiveSessions(userID int) ([]models.Session, error)
+	TouchSession(familyID string, expiresAt time.Time) (int, error)
+	HasSession(familyID string) (bool, error)
+	RevokeUserSessions(userID int) error
+}
+
+type SessionRepository struct {
+	DB *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *SessionRepository {
+	return &SessionRepository{DB: db}
+}
+
+func (r *SessionRepository) CreateSession(session models.Session) (int, error) {
+	var id int
+	err := r.DB.QueryRow(`
+       INSERT INTO sessions (user_id, family_id, user_agent, ip_address, expires_at)
+       VALUES ($1, $2, $3, $4, $5) RETURNING id
+   `, session.UserID, session.FamilyID, session.UserAgent, session.IPAddress, session.ExpiresAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *SessionRepository) GetSession(id int) (models.Session, error) {
+	var session models.Session
+	err := r.DB.QueryRow(`
+       SELECT id, user_id, family_id, user_agent, ip_address, expires_at, last_seen_at, revoked_at, created_at
+       FROM sessions
+       WHERE id = $1
+   `, id).Scan(&session.ID, &session.UserID, &session.FamilyID, &session.UserAgent, &session.IPAddress,
+		&session.ExpiresAt, &session.LastSeenAt, &session.RevokedAt, &session.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Session{}, ErrSessionNotFound
+		}
+		return models.Session{}, err
+	}
+	return session, nil
+}
+
+// GetActiveSessions returns the user's sessions that are neither revoked nor
+// expired, most recently used first.
+func (r *SessionRepository) GetActiveSessions(userID int) ([]models.Session, error) {
+	rows, err := r.DB.Query(`
+       SELECT id, user_id, family_id, user_agent, ip_address, expires_at, last_seen_at, revoked_at, created_at
+       FROM sessions
+       WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+       ORDER BY last_seen_at DESC, id DESC
+   `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.FamilyID, &session.UserAgent, &session.IPAddress,
+			&session.ExpiresAt, &session.LastSeenAt, &session.RevokedAt, &session.CreatedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// TouchSession records activity on the session of a refresh token family and
+// extends it to the new refresh token's expiry. It returns the session ID.
+func (r *SessionRepository) TouchSession(familyID string, expiresAt time.Time) (int, error) {
+	var id int
+	err := r.DB.QueryRow(`
+       UPDATE sessions SET last_seen_at = NOW(), expires_at = $2
+       WHERE family_id = $1 AND revoked_at IS NULL
+       RETURNING id
+   `, familyID, expiresAt).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrSessionNotFound
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+// HasSession reports whether a session, revoked or not, was ever recorded for
+// the refresh token family.
+func (r *SessionRepository) HasSession(familyID string) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM sessions WHERE family_id = $1)", familyID).Scan(&exists)
+	return exists, err
+}
+
+func (r *SessionRepository) RevokeUserSessions(userID int) error {
+	_, err := r.DB.Exec("UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	return err
+}
@@ -3,18 +3,43 @@ package repositories
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"go-crud/internal/models"
 	"go-crud/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrEmailInUse   = errors.New("email address is already in use")
+	// ErrVersionConflict means the user was changed since the version the caller read.
+	ErrVersionConflict = errors.New("user has been modified since it was read")
 )
 
 // UserRepositoryInterface defines the methods for interacting with the user repository.
 type UserRepositoryInterface interface {
+	UserSearcher
 	GetAllUsers() ([]models.User, error)
+	ListUsers(params models.UserListParams) ([]models.User, error)
+	CountUsers(filter models.UserFilter) (int, error)
+	ExportUsers(filter models.UserFilter, each func(models.User) error) error
 	GetUserByID(id int) (models.User, error)
+	GetUserByIDIncludingDeleted(id int) (models.User, error)
 	CreateUser(user models.User) (int, error)
+	ImportUsers(users []models.User) (map[string]int, error)
+	ExistingEmails(emails []string) ([]string, error)
 	UpdateUser(id int, user models.User) error
-	DeleteUser(id int) error
+	DeleteUser(id int, version int) error
+	RestoreUser(id int) error
+	PurgeDeletedUsers(deletedBefore time.Time) (int64, error)
 	GetUserByEmail(email string) (models.User, error)
+	GetPasswordHash(id int) (string, error)
+	UpdatePassword(id int, passwordHash string) error
+	UpdatePasswordIfUnchanged(id int, oldHash, newHash string) error
+	MarkEmailVerified(id int, email string) error
 }
 
 type UserRepository struct {
@@ -25,8 +50,9 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{DB: db}
 }
 
+// GetAllUsers returns every user that hasn't been deleted.
 func (r *UserRepository) GetAllUsers() ([]models.User, error) {
-	rows, err := r.DB.Query("SELECT id, name, email FROM users")
+	rows, err := r.DB.Query("SELECT id, name, email, role, email_verified, version, created_at FROM users WHERE deleted_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +61,7 @@ func (r *UserRepository) GetAllUsers() ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email); err != nil {
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.EmailVerified, &user.Version, &user.CreatedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -43,12 +69,234 @@ func (r *UserRepository) GetAllUsers() ([]models.User, error) {
 	return users, nil
 }
 
+// userSortColumns maps the sort fields to columns, so that only known columns
+// are ever interpolated into queries.
+var userSortColumns = map[string]string{
+	models.SortByID:        "id",
+	models.SortByName:      "name",
+	models.SortByEmail:     "email",
+	models.SortByCreatedAt: "created_at",
+}
+
+// ListUsers returns one page of users. With params.After it continues after
+// the cursor position (keyset pagination), otherwise it skips params.Offset users.
+func (r *UserRepository) ListUsers(params models.UserListParams) ([]models.User, error) {
+	column, ok := userSortColumns[params.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field %q", params.Sort)
+	}
+	direction, comparison := "ASC", ">"
+	if params.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	conditions, args := userFilterConditions(params.UserFilter)
+	if params.After != nil {
+		if column == "id" {
+			args = append(args, params.After.ID)
+			conditions = append(conditions, fmt.Sprintf("id %s $%d", comparison, len(args)))
+		} else {
+			cast := ""
+			if column == "created_at" {
+				cast = "::TIMESTAMPTZ"
+			}
+			args = append(args, params.After.Value, params.After.ID)
+			conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d%s, $%d)", column, comparison, len(args)-1, cast, len(args)))
+		}
+	}
+
+	query := "SELECT id, name, email, role, email_verified, version, created_at, deleted_at FROM users"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if column == "id" {
+		query += fmt.Sprintf(" ORDER BY id %s", direction)
+	} else {
+		query += fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction)
+	}
+	args = append(args, params.Limit)
+	query += fmt.Sprintf(" LIMIT $%d", len(args))
+	if params.After == nil && params.Offset > 0 {
+		args = append(args, params.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.EmailVerified, &user.Version,
+			&user.CreatedAt, &user.DeletedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// CountUsers returns the number of users matching the filter.
+func (r *UserRepository) CountUsers(filter models.UserFilter) (int, error) {
+	conditions, args := userFilterConditions(filter)
+	query := "SELECT COUNT(*) FROM users"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var count int
+	err := r.DB.QueryRow(query, args...).Scan(&count)
+	return count, err
+}
+
+// userFilterConditions returns the WHERE conditions for the filter and their
+// arguments, numbered from $1. Deleted users are excluded unless requested.
+func userFilterConditions(filter models.UserFilter) ([]string, []any) {
+	var conditions []string
+	var args []any
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if filter.NamePrefix != "" {
+		args = append(args, escapeLike(strings.ToLower(filter.NamePrefix))+"%")
+		conditions = append(conditions, fmt.Sprintf("LOWER(name) LIKE $%d", len(args)))
+	}
+	if filter.EmailDomain != "" {
+		args = append(args, strings.ToLower(filter.EmailDomain))
+		conditions = append(conditions, fmt.Sprintf("LOWER(SPLIT_PART(email, '@', 2)) = $%d", len(args)))
+	}
+	return conditions, args
+}
+
+// exportFetchSize is the number of users fetched from the export cursor at a time.
+const exportFetchSize = 1000
+
+// ExportUsers calls each for every user matching the filter, in ID order. The
+// users are read through a server-side cursor, a batch at a time, so the
+// table is never loaded into memory. Exporting stops at the first error
+// returned by each.
+func (r *UserRepository) ExportUsers(filter models.UserFilter, each func(models.User) error) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	// The transaction only holds the cursor, so it is never committed
+	defer tx.Rollback()
+
+	conditions, args := userFilterConditions(filter)
+	query := "DECLARE user_export NO SCROLL CURSOR FOR SELECT id, name, email, role, email_verified, version, created_at, deleted_at FROM users"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if _, err := tx.Exec(query+" ORDER BY id", args...); err != nil {
+		return err
+	}
+
+	for {
+		rows, err := tx.Query(fmt.Sprintf("FETCH FORWARD %d FROM user_export", exportFetchSize))
+		if err != nil {
+			return err
+		}
+		fetched := 0
+		for rows.Next() {
+			fetched++
+			var user models.User
+			if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.EmailVerified, &user.Version,
+				&user.CreatedAt, &user.DeletedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			if err := each(user); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if fetched < exportFetchSize {
+			return nil
+		}
+	}
+}
+
+// Search finds users whose name or email matches the query by full-text
+// search on whole words or their prefixes, by trigram word similarity for
+// typos, or as a substring. Results are ranked by the text rank plus the best
+// word similarity, best first.
+func (r *UserRepository) Search(query string, limit int) ([]models.UserSearchResult, error) {
+	rows, err := r.DB.Query(`
+       SELECT id, name, email, role, email_verified, created_at,
+              ts_rank(search_vector, tsq) + GREATEST(word_similarity($2, name), word_similarity($2, email)) AS rank
+       FROM users, to_tsquery('simple', $1) AS tsq
+       WHERE deleted_at IS NULL
+         AND (search_vector @@ tsq
+              OR $2 <% name OR $2 <% email
+              OR name ILIKE $3 OR email ILIKE $3)
+       ORDER BY rank DESC, id
+       LIMIT $4
+   `, prefixTSQuery(query), query, "%"+escapeLike(query)+"%", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.UserSearchResult{}
+	for rows.Next() {
+		var result models.UserSearchResult
+		if err := rows.Scan(&result.ID, &result.Name, &result.Email, &result.Role, &result.EmailVerified,
+			&result.CreatedAt, &result.Rank); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// prefixTSQuery builds a tsquery matching documents containing every term of
+// the query as a word prefix. Terms are quoted, so that user input can't
+// inject tsquery operators.
+func prefixTSQuery(query string) string {
+	quote := strings.NewReplacer(`'`, `''`, `\`, `\\`)
+	terms := strings.Fields(query)
+	for i, term := range terms {
+		terms[i] = "'" + quote.Replace(term) + "':*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// escapeLike escapes the LIKE wildcards in s, using the default escape character.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// GetUserByID returns the user unless it has been deleted.
 func (r *UserRepository) GetUserByID(id int) (models.User, error) {
+	user, err := r.GetUserByIDIncludingDeleted(id)
+	if err != nil {
+		return models.User{}, err
+	}
+	if user.DeletedAt != nil {
+		return models.User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// GetUserByIDIncludingDeleted returns the user even if it has been deleted
+// but not purged yet.
+func (r *UserRepository) GetUserByIDIncludingDeleted(id int) (models.User, error) {
 	var user models.User
-	err := r.DB.QueryRow("SELECT id, name, email FROM users WHERE id = $1", id).Scan(&user.ID, &user.Name, &user.Email)
+	err := r.DB.QueryRow("SELECT id, name, email, role, email_verified, version, created_at, deleted_at FROM users WHERE id = $1", id).
+		Scan(&user.ID, &user.Name, &user.Email, &user.Role, &user.EmailVerified, &user.Version, &user.CreatedAt, &user.DeletedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.User{}, errors.New("user not found")
+			return models.User{}, ErrUserNotFound
 		}
 		return models.User{}, err
 	}
@@ -61,41 +309,239 @@ func (r *UserRepository) CreateUser(user models.User) (int, error) {
 		return 0, err
 	}
 
+	role := user.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+
 	var id int
-	err = r.DB.QueryRow("INSERT INTO users (name, email, password_hash) VALUES ($1, $2, $3) RETURNING id", user.Name, user.Email, hashedPassword).Scan(&id)
+	err = r.DB.QueryRow("INSERT INTO users (name, email, password_hash, role, email_verified) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		user.Name, user.Email, hashedPassword, role, user.EmailVerified).Scan(&id)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+			return 0, ErrEmailInUse
+		}
 		return 0, err
 	}
 	return id, nil
 }
 
+// ImportUsers inserts users with already hashed passwords in one transaction
+// using COPY, and returns their IDs by email address. It fails with
+// ErrEmailInUse, inserting none of them, if any email address is taken.
+func (r *UserRepository) ImportUsers(users []models.User) (map[string]int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(pq.CopyIn("users", "name", "email", "password_hash", "role"))
+	if err != nil {
+		return nil, err
+	}
+	emails := make([]string, len(users))
+	for i, user := range users {
+		role := user.Role
+		if role == "" {
+			role = models.RoleUser
+		}
+		if _, err := stmt.Exec(user.Name, user.Email, user.PasswordHash, role); err != nil {
+			stmt.Close()
+			return nil, err
+		}
+		emails[i] = user.Email
+	}
+	// The rows are only sent, and constraints checked, when the COPY is flushed
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+			return nil, ErrEmailInUse
+		}
+		return nil, err
+	}
+	if err := stmt.Close(); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query("SELECT id, email FROM users WHERE email = ANY($1) AND deleted_at IS NULL", pq.Array(emails))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]int, len(users))
+	for rows.Next() {
+		var id int
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			return nil, err
+		}
+		ids[email] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, tx.Commit()
+}
+
+// ExistingEmails returns which of the email addresses belong to users that
+// haven't been deleted.
+func (r *UserRepository) ExistingEmails(emails []string) ([]string, error) {
+	rows, err := r.DB.Query("SELECT email FROM users WHERE email = ANY($1) AND deleted_at IS NULL", pq.Array(emails))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var existing []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		existing = append(existing, email)
+	}
+	return existing, rows.Err()
+}
+
+// UpdateUser saves the user and increments its version. When user.Version is
+// set, the update only applies to that version of the user and fails with
+// ErrVersionConflict if it was changed in the meantime.
 func (r *UserRepository) UpdateUser(id int, user models.User) error {
 	query := `
        UPDATE users
-       SET name = $1, email = $2, password_hash = $3
-       WHERE id = $4
+       SET name = $1, email = $2, password_hash = COALESCE(NULLIF($3, ''), password_hash),
+           email_verified = CASE WHEN email = $2 THEN email_verified ELSE FALSE END,
+           version = version + 1, updated_at = NOW()
+       WHERE id = $4 AND deleted_at IS NULL AND ($5 = 0 OR version = $5)
    `
-	_, err := r.DB.Exec(query, user.Name, user.Email, user.PasswordHash, id)
+	result, err := r.DB.Exec(query, user.Name, user.Email, user.PasswordHash, id, user.Version)
 	if err != nil {
 		return err
 	}
-	return nil
+	return r.requireVersionAffected(result, id)
 }
 
-func (r *UserRepository) DeleteUser(id int) error {
-	_, err := r.DB.Exec("DELETE FROM users WHERE id = $1", id)
-	return err
+// DeleteUser soft-deletes the user; PurgeDeletedUsers removes it for good
+// after the retention period. A non-zero version must match the user's.
+func (r *UserRepository) DeleteUser(id int, version int) error {
+	result, err := r.DB.Exec(`
+       UPDATE users SET deleted_at = NOW(), version = version + 1, updated_at = NOW()
+       WHERE id = $1 AND deleted_at IS NULL AND ($2 = 0 OR version = $2)
+   `, id, version)
+	if err != nil {
+		return err
+	}
+	return r.requireVersionAffected(result, id)
+}
+
+// RestoreUser undoes the deletion of a user that hasn't been purged yet. It
+// fails with ErrEmailInUse if another account took the email address since.
+func (r *UserRepository) RestoreUser(id int) error {
+	result, err := r.DB.Exec(`
+       UPDATE users SET deleted_at = NULL, version = version + 1, updated_at = NOW()
+       WHERE id = $1 AND deleted_at IS NOT NULL
+   `, id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" { // unique_violation
+			return ErrEmailInUse
+		}
+		return err
+	}
+	return requireUserAffected(result)
+}
+
+// PurgeDeletedUsers permanently deletes the users deleted before the given
+// time, along with their dependent records, and returns how many were removed.
+func (r *UserRepository) PurgeDeletedUsers(deletedBefore time.Time) (int64, error) {
+	result, err := r.DB.Exec("DELETE FROM users WHERE deleted_at < $1", deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// requireVersionAffected tells apart, when a conditional update matched no
+// rows, a user that doesn't exist from one at another version.
+func (r *UserRepository) requireVersionAffected(result sql.Result, id int) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = r.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)", id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return ErrUserNotFound
+}
+
+func requireUserAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
 }
 
 func (r *UserRepository) GetUserByEmail(email string) (models.User, error) {
 	var user models.User
-	err := r.DB.QueryRow("SELECT id, name, email, password_hash FROM users WHERE email = $1", email).
-		Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash)
+	err := r.DB.QueryRow(`
+       SELECT id, name, email, password_hash, role, email_verified, version, created_at
+       FROM users
+       WHERE email = $1 AND deleted_at IS NULL
+   `, email).Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.Role, &user.EmailVerified, &user.Version, &user.CreatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return models.User{}, errors.New("user not found")
+			return models.User{}, ErrUserNotFound
 		}
 		return models.User{}, err
 	}
 	return user, nil
 }
+
+func (r *UserRepository) GetPasswordHash(id int) (string, error) {
+	var passwordHash string
+	err := r.DB.QueryRow("SELECT password_hash FROM users WHERE id = $1", id).Scan(&passwordHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	return passwordHash, nil
+}
+
+// UpdatePassword stores an already hashed password.
+func (r *UserRepository) UpdatePassword(id int, passwordHash string) error {
+	_, err := r.DB.Exec("UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2", passwordHash, id)
+	return err
+}
+
+// UpdatePasswordIfUnchanged replaces the stored hash only while it is still
+// oldHash. When the password was changed meanwhile nothing is updated.
+func (r *UserRepository) UpdatePasswordIfUnchanged(id int, oldHash, newHash string) error {
+	_, err := r.DB.Exec("UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2 AND password_hash = $3", newHash, id, oldHash)
+	return err
+}
+
+// MarkEmailVerified marks the address as verified, unless the user's email
+// was changed to another address meanwhile.
+func (r *UserRepository) MarkEmailVerified(id int, email string) error {
+	_, err := r.DB.Exec("UPDATE users SET email_verified = TRUE, version = version + 1, updated_at = NOW() WHERE id = $1 AND email = $2 AND NOT email_verified", id, email)
+	return err
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"go-crud/internal/models"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// UserSearcher finds users by partial name or email.
+type UserSearcher interface {
+	Search(query string, limit int) ([]models.UserSearchResult, error)
+}
+
+// wordSimilarityThreshold matches the default pg_trgm.word_similarity_threshold.
+const wordSimilarityThreshold = 0.6
+
+// InMemoryUserSearch is a UserSearcher over a fixed list of users, for tests
+// that exercise search ranking without a database. It approximates
+// UserRepository.Search: word prefixes and substrings match, and trigram word
+// similarity catches typos.
+type InMemoryUserSearch struct {
+	Users []models.User
+}
+
+func NewInMemoryUserSearch(users []models.User) *InMemoryUserSearch {
+	return &InMemoryUserSearch{Users: users}
+}
+
+func (s *InMemoryUserSearch) Search(query string, limit int) ([]models.UserSearchResult, error) {
+	query = strings.ToLower(query)
+	terms := strings.Fields(query)
+
+	results := []models.UserSearchResult{}
+	for _, user := range s.Users {
+		name, email := strings.ToLower(user.Name), strings.ToLower(user.Email)
+		similarity := max(wordSimilarity(query, name), wordSimilarity(query, email))
+
+		rank := similarity
+		matched := similarity >= wordSimilarityThreshold ||
+			strings.Contains(name, query) || strings.Contains(email, query)
+		if len(terms) > 0 && allPrefixMatch(terms, strings.Fields(name+" "+email)) {
+			matched = true
+			rank += 0.1
+		}
+		if matched {
+			results = append(results, models.UserSearchResult{User: user, Rank: rank})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// allPrefixMatch reports whether every term is a prefix of one of the words.
+func allPrefixMatch(terms, words []string) bool {
+	for _, term := range terms {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// wordSimilarity approximates pg_trgm's word_similarity: the largest share of
+// the query's trigrams found in a single word of the text.
+func wordSimilarity(query, text string) float64 {
+	tq := trigrams(query)
+	if len(tq) == 0 {
+		return 0
+	}
+	best := 0.0
+	for _, word := range strings.FieldsFunc(text, isWordSeparator) {
+		tw := trigrams(word)
+		shared := 0
+		for t := range tq {
+			if tw[t] {
+				shared++
+			}
+		}
+		best = max(best, float64(shared)/float64(len(tq)))
+	}
+	return best
+}
+
+// trigrams returns the set of trigrams of the alphanumeric words of s, each
+// padded with two spaces in front and one behind as pg_trgm does.
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), isWordSeparator) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
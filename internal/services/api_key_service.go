@@ -0,0 +1,149 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"log"
+	"strings"
+	"time"
+)
+
+// apiKeyMarker starts every API key so leaked keys are easy to recognise,
+// e.g. by secret scanners. It is followed by the prefix and the secret.
+const apiKeyMarker = "gck_"
+
+// apiKeyPrefixLength is the number of hex characters in the public key prefix.
+const apiKeyPrefixLength = 12
+
+var ErrInvalidAPIKeyExpiry = errors.New("expires_at must be in the future")
+
+// APIKeyService manages API keys and authenticates requests made with them.
+type APIKeyService struct {
+	Repo  repositories.APIKeyRepositoryInterface
+	Users repositories.UserRepositoryInterface
+}
+
+func NewAPIKeyService(repo repositories.APIKeyRepositoryInterface, users repositories.UserRepositoryInterface) *APIKeyService {
+	return &APIKeyService{Repo: repo, Users: users}
+}
+
+// CreateKey creates an API key for the user and returns it with the plaintext
+// key, which can't be recovered later.
+func (s *APIKeyService) CreateKey(userID int, req models.CreateAPIKeyRequest) (models.APIKey, string, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return models.APIKey{}, "", ErrInvalidAPIKeyExpiry
+	}
+
+	prefix, err := generateAPIKeyPrefix()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	secret, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	plaintext := apiKeyMarker + prefix + "_" + secret
+
+	key, err := s.Repo.CreateAPIKey(models.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashToken(plaintext),
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	return key, plaintext, nil
+}
+
+func (s *APIKeyService) ListKeys(userID int) ([]models.APIKey, error) {
+	return s.Repo.GetAPIKeysByUser(userID)
+}
+
+func (s *APIKeyService) GetKey(userID, id int) (models.APIKey, error) {
+	return s.Repo.GetAPIKey(userID, id)
+}
+
+// UpdateKey renames the key or replaces its scopes.
+func (s *APIKeyService) UpdateKey(userID, id int, req models.UpdateAPIKeyRequest) (models.APIKey, error) {
+	key, err := s.Repo.GetAPIKey(userID, id)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	if req.Name != nil {
+		key.Name = *req.Name
+	}
+	if req.Scopes != nil {
+		key.Scopes = *req.Scopes
+	}
+	if err := s.Repo.UpdateAPIKey(key); err != nil {
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) DeleteKey(userID, id int) error {
+	return s.Repo.DeleteAPIKey(userID, id)
+}
+
+// AuthenticateAPIKey returns the owner, the owner's current role and the
+// scopes of a valid, unexpired key. ok is false for unknown or expired keys.
+func (s *APIKeyService) AuthenticateAPIKey(plaintext string) (userID int, role string, scopes []string, ok bool, err error) {
+	prefix, found := parseAPIKeyPrefix(plaintext)
+	if !found {
+		return 0, "", nil, false, nil
+	}
+
+	key, err := s.Repo.GetAPIKeyByPrefix(prefix)
+	if err != nil {
+		if errors.Is(err, repositories.ErrAPIKeyNotFound) {
+			return 0, "", nil, false, nil
+		}
+		return 0, "", nil, false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(utils.HashToken(plaintext))) != 1 {
+		return 0, "", nil, false, nil
+	}
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return 0, "", nil, false, nil
+	}
+
+	// Load the owner so role changes apply to existing keys
+	owner, err := s.Users.GetUserByID(key.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return 0, "", nil, false, nil
+		}
+		return 0, "", nil, false, err
+	}
+
+	// The last-used timestamp is informational, so failing to record it doesn't fail the request
+	if err := s.Repo.TouchAPIKey(key.ID); err != nil {
+		log.Printf("Error recording API key use: %v", err)
+	}
+	return owner.ID, owner.Role, key.Scopes, true, nil
+}
+
+// parseAPIKeyPrefix extracts the prefix from a key of the form gck_<prefix>_<secret>.
+func parseAPIKeyPrefix(plaintext string) (string, bool) {
+	rest, found := strings.CutPrefix(plaintext, apiKeyMarker)
+	if !found || len(rest) <= apiKeyPrefixLength+1 || rest[apiKeyPrefixLength] != '_' {
+		return "", false
+	}
+	return rest[:apiKeyPrefixLength], true
+}
+
+func generateAPIKeyPrefix() (string, error) {
+	b := make([]byte, apiKeyPrefixLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
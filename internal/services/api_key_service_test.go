@@ -0,0 +1,149 @@
+package services
+
+import (
+	"encoding/json"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKey_CreateAndAuthenticate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockAPIKeyRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewAPIKeyService(mockRepo, mockUsers)
+
+	var stored models.APIKey
+
+	// Mock repository behavior
+	mockRepo.EXPECT().CreateAPIKey(gomock.Any()).DoAndReturn(func(key models.APIKey) (models.APIKey, error) {
+		key.ID = 3
+		stored = key
+		return key, nil
+	})
+
+	// Call the method
+	key, plaintext, err := service.CreateKey(1, models.CreateAPIKeyRequest{Name: "nightly sync", Scopes: []string{models.ScopeUsersRead}})
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plaintext, apiKeyMarker+key.Prefix+"_"))
+	assert.NotContains(t, stored.KeyHash, plaintext)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetAPIKeyByPrefix(key.Prefix).Return(stored, nil)
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Role: models.RoleAdmin}, nil)
+	mockRepo.EXPECT().TouchAPIKey(3).Return(nil)
+
+	// Call the method
+	userID, role, scopes, ok, err := service.AuthenticateAPIKey(plaintext)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, userID)
+	assert.Equal(t, models.RoleAdmin, role)
+	assert.Equal(t, []string{models.ScopeUsersRead}, scopes)
+}
+
+func TestAPIKey_AuthenticateRejectsWrongSecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockAPIKeyRepositoryInterface(ctrl)
+	service := NewAPIKeyService(mockRepo, nil)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetAPIKeyByPrefix("0123456789ab").Return(models.APIKey{
+		ID:      3,
+		UserID:  1,
+		Prefix:  "0123456789ab",
+		KeyHash: "not-the-hash",
+	}, nil)
+
+	// Call the method
+	_, _, _, ok, err := service.AuthenticateAPIKey("gck_0123456789ab_guessed-secret")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAPIKey_AuthenticateRejectsExpiredKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockAPIKeyRepositoryInterface(ctrl)
+	service := NewAPIKeyService(mockRepo, nil)
+
+	// Create a key that expires later
+	var stored models.APIKey
+	mockRepo.EXPECT().CreateAPIKey(gomock.Any()).DoAndReturn(func(key models.APIKey) (models.APIKey, error) {
+		stored = key
+		return key, nil
+	})
+	expiresAt := time.Now().Add(time.Hour)
+	_, plaintext, err := service.CreateKey(1, models.CreateAPIKeyRequest{Name: "ci", Scopes: []string{models.ScopeUsersRead}, ExpiresAt: &expiresAt})
+	assert.NoError(t, err)
+
+	// Mock repository behavior: the key has since expired
+	expired := time.Now().Add(-time.Minute)
+	stored.ExpiresAt = &expired
+	mockRepo.EXPECT().GetAPIKeyByPrefix(stored.Prefix).Return(stored, nil)
+
+	// Call the method
+	_, _, _, ok, err := service.AuthenticateAPIKey(plaintext)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAPIKey_AuthenticateIgnoresMalformedKeys(t *testing.T) {
+	service := NewAPIKeyService(nil, nil)
+
+	for _, key := range []string{"", "gck_", "gck_short_secret", "xyz_0123456789ab_secret", "gck_0123456789ab"} {
+		_, _, _, ok, err := service.AuthenticateAPIKey(key)
+		assert.NoError(t, err)
+		assert.False(t, ok, key)
+	}
+}
+
+func TestAPIKey_CreateRejectsPastExpiry(t *testing.T) {
+	service := NewAPIKeyService(nil, nil)
+
+	expiresAt := time.Now().Add(-time.Hour)
+	_, _, err := service.CreateKey(1, models.CreateAPIKeyRequest{Name: "ci", Scopes: []string{models.ScopeUsersRead}, ExpiresAt: &expiresAt})
+
+	assert.ErrorIs(t, err, ErrInvalidAPIKeyExpiry)
+}
+
+func TestAPIKey_UpdateRequestRejectsEmptyScopes(t *testing.T) {
+	tests := []struct {
+		body  string
+		valid bool
+	}{
+		{`{"name": "ci"}`, true},
+		{`{"scopes": null}`, true},
+		{`{"scopes": ["users:read"]}`, true},
+		{`{"scopes": []}`, false},
+		{`{"scopes": ["users:admin"]}`, false},
+	}
+
+	for _, tt := range tests {
+		var req models.UpdateAPIKeyRequest
+		assert.NoError(t, json.Unmarshal([]byte(tt.body), &req))
+		err := models.Validate.Struct(req)
+		assert.Equal(t, tt.valid, err == nil, tt.body)
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// maxUserAgentLength bounds the user agent stored with a session.
+const maxUserAgentLength = 512
+
+// AuthService issues access/refresh token pairs, rotates refresh tokens and
+// revokes sessions. Each login starts a session, which lists the device it
+// came from and can be revoked on its own.
+type AuthService struct {
+	Repo        repositories.RefreshTokenRepositoryInterface
+	Sessions    repositories.SessionRepositoryInterface
+	Users       repositories.UserRepositoryInterface
+	Revocations *RevocationService
+	Tokens      *utils.TokenService
+	OAuth       repositories.OAuthRepositoryInterface // Optional; RevokeAllForUser also revokes OAuth refresh tokens when set
+}
+
+func NewAuthService(repo repositories.RefreshTokenRepositoryInterface, sessions repositories.SessionRepositoryInterface,
+	users repositories.UserRepositoryInterface, revocations *RevocationService, tokens *utils.TokenService) *AuthService {
+	return &AuthService{Repo: repo, Sessions: sessions, Users: users, Revocations: revocations, Tokens: tokens}
+}
+
+// IssueTokens starts a new session and refresh token family for the user on
+// login, recording the client's user agent and IP address.
+func (s *AuthService) IssueTokens(user models.User, userAgent, ip string) (models.TokenResponse, error) {
+	familyID, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	sessionID, err := s.Sessions.CreateSession(models.Session{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		UserAgent: userAgent,
+		IPAddress: ip,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+	})
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	return s.issue(user, familyID, sessionID)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Each refresh token
+// can be used once; presenting one that was already used revokes its whole
+// family, since either the client or an attacker holds a stolen copy.
+func (s *AuthService) Refresh(refreshToken string) (models.TokenResponse, error) {
+	stored, err := s.Repo.GetRefreshTokenByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repositories.ErrRefreshTokenNotFound) {
+			return models.TokenResponse{}, ErrInvalidRefreshToken
+		}
+		return models.TokenResponse{}, err
+	}
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return models.TokenResponse{}, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return models.TokenResponse{}, s.revokeFamily(stored.FamilyID)
+	}
+
+	// Reload the user so role changes take effect on the next refresh.
+	user, err := s.Users.GetUserByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return models.TokenResponse{}, ErrInvalidRefreshToken
+		}
+		return models.TokenResponse{}, err
+	}
+
+	marked, err := s.Repo.MarkRefreshTokenUsed(stored.ID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+	if !marked {
+		// Another request consumed the token between our read and update.
+		return models.TokenResponse{}, s.revokeFamily(stored.FamilyID)
+	}
+
+	sessionID, err := s.Sessions.TouchSession(stored.FamilyID, time.Now().Add(utils.RefreshTokenTTL))
+	if errors.Is(err, repositories.ErrSessionNotFound) {
+		// Families started before sessions were introduced have none; any
+		// other family without an active session has been logged out
+		hasSession, err := s.Sessions.HasSession(stored.FamilyID)
+		if err != nil {
+			return models.TokenResponse{}, err
+		}
+		if hasSession {
+			return models.TokenResponse{}, ErrInvalidRefreshToken
+		}
+	} else if err != nil {
+		return models.TokenResponse{}, err
+	}
+	return s.issue(user, stored.FamilyID, sessionID)
+}
+
+// Logout revokes the access token identified by jti and its session or, for
+// tokens without a session, the refresh token family when given and owned by
+// the user.
+func (s *AuthService) Logout(jti string, expiresAt time.Time, userID, sessionID int, refreshToken string) error {
+	if err := s.Revocations.RevokeToken(jti, expiresAt); err != nil {
+		return err
+	}
+	if sessionID != 0 {
+		if err := s.RevokeSession(userID, sessionID); err != nil && !errors.Is(err, repositories.ErrSessionNotFound) {
+			return err
+		}
+		return nil
+	}
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := s.Repo.GetRefreshTokenByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repositories.ErrRefreshTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+	// Someone else's refresh token is ignored like an unknown one
+	if stored.UserID != userID {
+		return nil
+	}
+	return s.Repo.RevokeRefreshTokenFamily(stored.FamilyID)
+}
+
+// ListSessions returns the user's active sessions.
+func (s *AuthService) ListSessions(userID int) ([]models.Session, error) {
+	return s.Sessions.GetActiveSessions(userID)
+}
+
+// RevokeSession signs the user out of one session: its refresh tokens and the
+// access tokens issued for it stop working, while other sessions are unaffected.
+func (s *AuthService) RevokeSession(userID, sessionID int) error {
+	session, err := s.Sessions.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return repositories.ErrSessionNotFound
+	}
+
+	if err := s.Repo.RevokeRefreshTokenFamily(session.FamilyID); err != nil {
+		return err
+	}
+	return s.Revocations.RevokeSession(sessionID)
+}
+
+// RevokeAllForUser revokes every outstanding access and refresh token of the
+// user, including the refresh tokens held by OAuth clients.
+func (s *AuthService) RevokeAllForUser(userID int) error {
+	if err := s.Repo.RevokeUserRefreshTokens(userID); err != nil {
+		return err
+	}
+	if s.OAuth != nil {
+		if err := s.OAuth.RevokeUserOAuthRefreshTokens(userID); err != nil {
+			return err
+		}
+	}
+	if err := s.Sessions.RevokeUserSessions(userID); err != nil {
+		return err
+	}
+	return s.Revocations.RevokeUserTokens(userID)
+}
+
+func (s *AuthService) revokeFamily(familyID string) error {
+	if err := s.Repo.RevokeRefreshTokenFamily(familyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func (s *AuthService) issue(user models.User, familyID string, sessionID int) (models.TokenResponse, error) {
+	accessToken, err := s.Tokens.IssueAccessToken(user.ID, user.Role, sessionID)
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	_, err = s.Repo.CreateRefreshToken(models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+	})
+	if err != nil {
+		return models.TokenResponse{}, err
+	}
+
+	return models.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.Tokens.AccessTokenTTL.Seconds()),
+	}, nil
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTokenService() *utils.TokenService {
+	return utils.NewTokenService(utils.NewHMACKeySet([]byte("test-secret")), "test-issuer", "test-audience")
+}
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockRefreshTokenRepositoryInterface(ctrl)
+	mockSessions := repositories.NewMockSessionRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	tokens := newTestTokenService()
+	service := NewAuthService(mockRepo, mockSessions, mockUsers, nil, tokens)
+
+	stored := models.RefreshToken{
+		ID:        7,
+		UserID:    1,
+		FamilyID:  "family",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetRefreshTokenByHash(utils.HashToken("old-token")).Return(stored, nil)
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Role: models.RoleUser}, nil)
+	mockRepo.EXPECT().MarkRefreshTokenUsed(7).Return(true, nil)
+	mockSessions.EXPECT().TouchSession("family", gomock.Any()).Return(4, nil)
+	mockRepo.EXPECT().CreateRefreshToken(gomock.Any()).DoAndReturn(func(token models.RefreshToken) (int, error) {
+		assert.Equal(t, 1, token.UserID)
+		assert.Equal(t, "family", token.FamilyID)
+		return 8, nil
+	})
+
+	// Call the method
+	result, err := service.Refresh("old-token")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+	assert.NotEmpty(t, result.RefreshToken)
+	assert.NotEqual(t, "old-token", result.RefreshToken)
+
+	// The new access token stays bound to the session
+	claims, err := tokens.ParseAccessToken(result.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, claims.SessionID)
+}
+
+func TestRefresh_RevokedSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockRefreshTokenRepositoryInterface(ctrl)
+	mockSessions := repositories.NewMockSessionRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewAuthService(mockRepo, mockSessions, mockUsers, nil, newTestTokenService())
+
+	stored := models.RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetRefreshTokenByHash(gomock.Any()).Return(stored, nil)
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Role: models.RoleUser}, nil)
+	mockRepo.EXPECT().MarkRefreshTokenUsed(7).Return(true, nil)
+	mockSessions.EXPECT().TouchSession("family", gomock.Any()).Return(0, repositories.ErrSessionNotFound)
+	mockSessions.EXPECT().HasSession("family").Return(true, nil)
+
+	// Call the method
+	_, err := service.Refresh("old-token")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefresh_FamilyWithoutSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockRefreshTokenRepositoryInterface(ctrl)
+	mockSessions := repositories.NewMockSessionRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	tokens := newTestTokenService()
+	service := NewAuthService(mockRepo, mockSessions, mockUsers, nil, tokens)
+
+	stored := models.RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetRefreshTokenByHash(gomock.Any()).Return(stored, nil)
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Role: models.RoleUser}, nil)
+	mockRepo.EXPECT().MarkRefreshTokenUsed(7).Return(true, nil)
+	mockSessions.EXPECT().TouchSession("family", gomock.Any()).Return(0, repositories.ErrSessionNotFound)
+	mockSessions.EXPECT().HasSession("family").Return(false, nil)
+	mockRepo.EXPECT().CreateRefreshToken(gomock.Any()).Return(8, nil)
+
+	// Call the method
+	result, err := service.Refresh("old-token")
+
+	// Assertions
+	assert.NoError(t, err)
+	claims, err := tokens.ParseAccessToken(result.Token)
+	assert.NoError(t, err)
+	assert.Zero(t, claims.SessionID)
+}
+
+func TestRefresh_ReuseRevokesFamily(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockRefreshTokenRepositoryInterface(ctrl)
+	service := NewAuthService(mockRepo, nil, nil, nil, newTestTokenService())
+
+	usedAt := time.Now().Add(-time.Minute)
+	stored := models.RefreshToken{
+		ID:        7,
+		UserID:    1,
+		FamilyID:  "family",
+		ExpiresAt: time.Now().Add(time.Hour),
+		UsedAt:    &usedAt,
+	}
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetRefreshTokenByHash(gomock.Any()).Return(stored, nil)
+	mockRepo.EXPECT().RevokeRefreshTokenFamily("family").Return(nil)
+
+	// Call the method
+	_, err := service.Refresh("old-token")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+}
+
+func TestRefresh_Expired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockRefreshTokenRepositoryInterface(ctrl)
+	service := NewAuthService(mockRepo, nil, nil, nil, newTestTokenService())
+
+	stored := models.RefreshToken{
+		ID:        7,
+		UserID:    1,
+		FamilyID:  "family",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetRefreshTokenByHash(gomock.Any()).Return(stored, nil)
+
+	// Call the method
+	_, err := service.Refresh("old-token")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestIssueTokens_StartsSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockRepo := repositories.NewMockRefreshTokenRepositoryInterface(ctrl)
+	mockSessions := repositories.NewMockSessionRepositoryInterface(ctrl)
+	tokens := newTestTokenService()
+	service := NewAuthService(mockRepo, mockSessions, nil, nil, tokens)
+
+	var session models.Session
+
+	// Mock repository behavior
+	mockSessions.EXPECT().CreateSession(gomock.Any()).DoAndReturn(func(s models.Session) (int, error) {
+		session = s
+		return 4, nil
+	})
+	mockRepo.EXPECT().CreateRefreshToken(gomock.Any()).DoAndReturn(func(token models.RefreshToken) (int, error) {
+		assert.Equal(t, session.FamilyID, token.FamilyID)
+		return 8, nil
+	})
+
+	// Call the method
+	result, err := service.IssueTokens(models.User{ID: 1, Role: models.RoleUser}, "Mozilla/5.0", "203.0.113.7")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 1, session.UserID)
+	assert.Equal(t, "Mozilla/5.0", session.UserAgent)
+	assert.Equal(t, "203.0.113.7", session.IPAddress)
+	claims, err := tokens.ParseAccessToken(result.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, claims.SessionID)
+}
+
+func TestRevokeSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockRepo := repositories.NewMockRefreshTokenRepositoryInterface(ctrl)
+	mockSessions := repositories.NewMockSessionRepositoryInterface(ctrl)
+	mockRevocations := repositories.NewMockRevocationRepositoryInterface(ctrl)
+	service := NewAuthService(mockRepo, mockSessions, nil, NewRevocationService(mockRevocations), newTestTokenService())
+
+	// Mock repository behavior
+	mockSessions.EXPECT().GetSession(4).Return(models.Session{ID: 4, UserID: 1, FamilyID: "family"}, nil).Times(2)
+	mockRepo.EXPECT().RevokeRefreshTokenFamily("family").Return(nil)
+	mockRevocations.EXPECT().RevokeSession(4).Return(nil)
+
+	// Sessions of other users can't be revoked
+	err := service.RevokeSession(2, 4)
+	assert.ErrorIs(t, err, repositories.ErrSessionNotFound)
+
+	// Call the method
+	err = service.RevokeSession(1, 4)
+
+	// Assertions
+	assert.NoError(t, err)
+}
+
+func TestLogout_OnlyRevokesOwnRefreshTokenFamily(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockRepo := repositories.NewMockRefreshTokenRepositoryInterface(ctrl)
+	mockRevocations := repositories.NewMockRevocationRepositoryInterface(ctrl)
+	service := NewAuthService(mockRepo, nil, nil, NewRevocationService(mockRevocations), newTestTokenService())
+	expiresAt := time.Now().Add(time.Hour)
+
+	// Mock repository behavior
+	mockRevocations.EXPECT().RevokeToken(gomock.Any(), expiresAt).Return(nil).Times(2)
+	mockRepo.EXPECT().GetRefreshTokenByHash(utils.HashToken("refresh")).
+		Return(models.RefreshToken{ID: 7, UserID: 1, FamilyID: "family"}, nil).Times(2)
+	mockRepo.EXPECT().RevokeRefreshTokenFamily("family").Return(nil)
+
+	// Another user's refresh token is left alone
+	err := service.Logout("other-jti", expiresAt, 2, 0, "refresh")
+	assert.NoError(t, err)
+
+	// Call the method
+	err = service.Logout("jti", expiresAt, 1, 0, "refresh")
+
+	// Assertions
+	assert.NoError(t, err)
+}
+
+func TestRefresh_DeletedUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockRepo := repositories.NewMockRefreshTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewAuthService(mockRepo, nil, mockUsers, nil, newTestTokenService())
+
+	stored := models.RefreshToken{ID: 7, UserID: 1, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetRefreshTokenByHash(gomock.Any()).Return(stored, nil)
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{}, repositories.ErrUserNotFound)
+
+	// Call the method
+	_, err := service.Refresh("old-token")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EmailVerificationTTL  = 48 * time.Hour // How long a verification token stays valid
+	DefaultResendInterval = time.Minute    // Minimum time between two verification emails
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailNotVerified         = errors.New("email address not verified")
+)
+
+// ErrVerificationThrottled is returned when a verification email was sent too recently.
+type ErrVerificationThrottled struct {
+	RetryAfter time.Duration
+}
+
+func (e *ErrVerificationThrottled) Error() string {
+	return fmt.Sprintf("verification email recently sent, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// maxResendEntries triggers a sweep of expired resend throttle entries.
+const maxResendEntries = 10000
+
+// EmailVerificationService sends email verification tokens and redeems them.
+// Each token is bound to the address it was sent to.
+type EmailVerificationService struct {
+	Tokens         repositories.ActionTokenRepositoryInterface
+	Users          repositories.UserRepositoryInterface
+	Notifier       Mailer
+	VerifyURL      string        // Link sent to users; the token is appended as a query parameter
+	ResendInterval time.Duration // Throttle for SendVerification and Resend
+	Required       bool          // Whether unverified accounts are blocked from logging in
+
+	mu      sync.Mutex
+	resends map[string]time.Time // Last Resend request per email address
+}
+
+func NewEmailVerificationService(tokens repositories.ActionTokenRepositoryInterface, users repositories.UserRepositoryInterface,
+	notifier Mailer, verifyURL string, required bool) *EmailVerificationService {
+	return &EmailVerificationService{
+		Tokens:         tokens,
+		Users:          users,
+		Notifier:       notifier,
+		VerifyURL:      verifyURL,
+		ResendInterval: DefaultResendInterval,
+		Required:       required,
+		resends:        make(map[string]time.Time),
+	}
+}
+
+// CanLogin enforces the verification policy for a user who passed authentication.
+func (s *EmailVerificationService) CanLogin(user models.User) error {
+	if s.Required && !user.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+// SendVerification sends a verification token to the user, unless one was
+// sent within ResendInterval.
+func (s *EmailVerificationService) SendVerification(user models.User) error {
+	last, err := s.Tokens.GetLatestActionTokenCreatedAt(user.ID, models.PurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+	if wait := time.Until(last.Add(s.ResendInterval)); wait > 0 {
+		return &ErrVerificationThrottled{RetryAfter: wait}
+	}
+	return s.send(user)
+}
+
+// EmailChanged invalidates the verification tokens sent to the user's
+// previous address and sends a new one to the current address.
+func (s *EmailVerificationService) EmailChanged(user models.User) error {
+	if err := s.Tokens.InvalidateActionTokens(user.ID, models.PurposeEmailVerification); err != nil {
+		return err
+	}
+	return s.send(user)
+}
+
+// Resend sends a new verification token to the account with the given email.
+// Unknown and already verified accounts are ignored. Requests are throttled
+// per address whether or not an account exists, so the response doesn't
+// reveal which addresses are registered.
+func (s *EmailVerificationService) Resend(email string) error {
+	if wait := s.resendWait(email); wait > 0 {
+		return &ErrVerificationThrottled{RetryAfter: wait}
+	}
+
+	user, err := s.Users.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	// A token sent on registration is still throttled, but reporting it would
+	// single out existing accounts
+	var throttled *ErrVerificationThrottled
+	if err := s.SendVerification(user); err != nil && !errors.As(err, &throttled) {
+		return err
+	}
+	return nil
+}
+
+// Verify redeems a verification token and marks the user's email as verified.
+func (s *EmailVerificationService) Verify(token string) error {
+	stored, err := s.Tokens.GetActionTokenByHash(models.PurposeEmailVerification, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repositories.ErrActionTokenNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return ErrInvalidVerificationToken
+	}
+
+	// Tokens sent to a previous address don't verify the current one
+	user, err := s.Users.GetUserByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return ErrInvalidVerificationToken
+		}
+		return err
+	}
+	if stored.Email == "" || stored.Email != user.Email {
+		return ErrInvalidVerificationToken
+	}
+
+	marked, err := s.Tokens.MarkActionTokenUsed(stored.ID)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return ErrInvalidVerificationToken
+	}
+	return s.Users.MarkEmailVerified(user.ID, user.Email)
+}
+
+// send creates a verification token bound to the user's current address and emails it.
+func (s *EmailVerificationService) send(user models.User) error {
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Tokens.CreateActionToken(models.ActionToken{
+		UserID:    user.ID,
+		Purpose:   models.PurposeEmailVerification,
+		TokenHash: utils.HashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(EmailVerificationTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Use the following token to verify your email address: %s", token)
+	if s.VerifyURL != "" {
+		link, err := linkWithToken(s.VerifyURL, token)
+		if err != nil {
+			return err
+		}
+		body = fmt.Sprintf("Verify your email address using this link: %s", link)
+	}
+	return s.Notifier.Send(user.Email, "Verify your email address", body)
+}
+
+// resendWait records a Resend request for the address and returns how long
+// the caller must wait if the previous request was too recent.
+func (s *EmailVerificationService) resendWait(email string) time.Duration {
+	key := strings.ToLower(strings.TrimSpace(email))
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if wait := s.resends[key].Add(s.ResendInterval).Sub(now); wait > 0 {
+		return wait
+	}
+	if len(s.resends) >= maxResendEntries {
+		for address, last := range s.resends {
+			if now.Sub(last) >= s.ResendInterval {
+				delete(s.resends, address)
+			}
+		}
+	}
+	s.resends[key] = now
+	return 0
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendVerification_Throttled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	mailer := &fakeMailer{}
+	service := NewEmailVerificationService(mockTokens, mockUsers, mailer, "", true)
+
+	// Mock repository behavior: a token was sent 10 seconds ago
+	mockTokens.EXPECT().GetLatestActionTokenCreatedAt(1, models.PurposeEmailVerification).
+		Return(time.Now().Add(-10*time.Second), nil)
+
+	// Call the method
+	err := service.SendVerification(models.User{ID: 1, Email: "john@gmail.com"})
+
+	// Assertions
+	var throttled *ErrVerificationThrottled
+	assert.ErrorAs(t, err, &throttled)
+	assert.InDelta(t, 50, throttled.RetryAfter.Seconds(), 1)
+	assert.Equal(t, 0, mailer.sent)
+}
+
+func TestVerify_MarksEmailVerified(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewEmailVerificationService(mockTokens, mockUsers, &fakeMailer{}, "", true)
+
+	stored := models.ActionToken{ID: 4, UserID: 1, Email: "john@gmail.com", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Mock repository behavior
+	mockTokens.EXPECT().GetActionTokenByHash(models.PurposeEmailVerification, gomock.Any()).Return(stored, nil)
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Email: "john@gmail.com"}, nil)
+	mockTokens.EXPECT().MarkActionTokenUsed(4).Return(true, nil)
+	mockUsers.EXPECT().MarkEmailVerified(1, "john@gmail.com").Return(nil)
+
+	// Call the method
+	err := service.Verify("verification-token")
+
+	// Assertions
+	assert.NoError(t, err)
+}
+
+func TestVerify_RejectsTokenForPreviousEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewEmailVerificationService(mockTokens, mockUsers, &fakeMailer{}, "", true)
+
+	stored := models.ActionToken{ID: 4, UserID: 1, Email: "john@gmail.com", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Mock repository behavior: the user changed the address since
+	mockTokens.EXPECT().GetActionTokenByHash(models.PurposeEmailVerification, gomock.Any()).Return(stored, nil)
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Email: "mallory@example.com"}, nil)
+
+	// Call the method
+	err := service.Verify("verification-token")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+}
+
+func TestEmailChanged_ReplacesOutstandingTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mailer := &fakeMailer{}
+	service := NewEmailVerificationService(mockTokens, nil, mailer, "https://example.com/verify?lang=en", true)
+
+	// Mock repository behavior
+	gomock.InOrder(
+		mockTokens.EXPECT().InvalidateActionTokens(1, models.PurposeEmailVerification).Return(nil),
+		mockTokens.EXPECT().CreateActionToken(gomock.Any()).DoAndReturn(func(token models.ActionToken) (int, error) {
+			assert.Equal(t, "new@gmail.com", token.Email)
+			return 5, nil
+		}),
+	)
+
+	// Call the method
+	err := service.EmailChanged(models.User{ID: 1, Email: "new@gmail.com"})
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, "new@gmail.com", mailer.to)
+	assert.Contains(t, mailer.body, "https://example.com/verify?lang=en&token=")
+}
+
+func TestResend_ThrottlesUnknownEmails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewEmailVerificationService(mockTokens, mockUsers, &fakeMailer{}, "", true)
+
+	// Mock repository behavior: the address is looked up only once
+	mockUsers.EXPECT().GetUserByEmail("nobody@gmail.com").Return(models.User{}, repositories.ErrUserNotFound)
+
+	// Call the method twice
+	first := service.Resend("nobody@gmail.com")
+	second := service.Resend("Nobody@gmail.com")
+
+	// Assertions: unknown addresses are throttled like existing accounts
+	assert.NoError(t, first)
+	var throttled *ErrVerificationThrottled
+	assert.ErrorAs(t, second, &throttled)
+}
+
+func TestCanLogin_RequiresVerifiedEmail(t *testing.T) {
+	service := NewEmailVerificationService(nil, nil, nil, "", true)
+
+	assert.ErrorIs(t, service.CanLogin(models.User{EmailVerified: false}), ErrEmailNotVerified)
+	assert.NoError(t, service.CanLogin(models.User{EmailVerified: true}))
+
+	service.Required = false
+	assert.NoError(t, service.CanLogin(models.User{EmailVerified: false}))
+}
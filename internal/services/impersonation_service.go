@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"log"
+	"slices"
+)
+
+var (
+	ErrNotSupportUser    = errors.New("only support staff can impersonate users")
+	ErrCannotImpersonate = errors.New("this user cannot be impersonated")
+)
+
+// ImpersonationService lets support staff act as a user to reproduce what
+// they see. Every session is recorded, and the tokens name the staff member
+// in their act claim.
+type ImpersonationService struct {
+	Repo           repositories.ImpersonationRepositoryInterface
+	Users          repositories.UserRepositoryInterface
+	Tokens         *utils.TokenService
+	SupportUserIDs []int
+}
+
+func NewImpersonationService(repo repositories.ImpersonationRepositoryInterface, users repositories.UserRepositoryInterface,
+	tokens *utils.TokenService, supportUserIDs []int) *ImpersonationService {
+	return &ImpersonationService{Repo: repo, Users: users, Tokens: tokens, SupportUserIDs: supportUserIDs}
+}
+
+// Impersonate records an impersonation session and issues its token. Admins
+// can't be impersonated, so impersonation never grants elevated privileges.
+func (s *ImpersonationService) Impersonate(actorID, userID int, reason, ip string) (models.ImpersonationResponse, error) {
+	if !slices.Contains(s.SupportUserIDs, actorID) {
+		return models.ImpersonationResponse{}, ErrNotSupportUser
+	}
+	if actorID == userID {
+		return models.ImpersonationResponse{}, ErrCannotImpersonate
+	}
+
+	user, err := s.Users.GetUserByID(userID)
+	if err != nil {
+		return models.ImpersonationResponse{}, err
+	}
+	if user.Role == models.RoleAdmin {
+		return models.ImpersonationResponse{}, ErrCannotImpersonate
+	}
+
+	token, jti, expiresAt, err := s.Tokens.IssueImpersonationToken(user.ID, user.Role, actorID)
+	if err != nil {
+		return models.ImpersonationResponse{}, err
+	}
+
+	// The token is only handed out once the session is on record
+	sessionID, err := s.Repo.CreateImpersonationSession(models.ImpersonationSession{
+		ActorID:   &actorID,
+		UserID:    &user.ID,
+		Reason:    reason,
+		TokenID:   jti,
+		IPAddress: ip,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return models.ImpersonationResponse{}, err
+	}
+	log.Printf("Impersonation: user_id %d started session %d as user_id %d: %s", actorID, sessionID, user.ID, reason)
+
+	return models.ImpersonationResponse{
+		Token:     token,
+		ExpiresIn: int64(s.Tokens.ImpersonationTTL.Seconds()),
+		SessionID: sessionID,
+	}, nil
+}
+
+// ListSessions returns the recorded sessions, optionally only for one user.
+func (s *ImpersonationService) ListSessions(userID *int) ([]models.ImpersonationSession, error) {
+	return s.Repo.GetImpersonationSessions(userID)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpersonate_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockRepo := repositories.NewMockImpersonationRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	tokens := newTestTokenService()
+	service := NewImpersonationService(mockRepo, mockUsers, tokens, []int{3})
+
+	var recorded models.ImpersonationSession
+
+	// Mock repository behavior
+	mockUsers.EXPECT().GetUserByID(42).Return(models.User{ID: 42, Role: models.RoleUser}, nil)
+	mockRepo.EXPECT().CreateImpersonationSession(gomock.Any()).DoAndReturn(func(session models.ImpersonationSession) (int, error) {
+		recorded = session
+		return 9, nil
+	})
+
+	// Call the method
+	response, err := service.Impersonate(3, 42, "Ticket #1234", "203.0.113.7")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 9, response.SessionID)
+	assert.Equal(t, 3, *recorded.ActorID)
+	assert.Equal(t, 42, *recorded.UserID)
+	assert.Equal(t, "Ticket #1234", recorded.Reason)
+	assert.Equal(t, "203.0.113.7", recorded.IPAddress)
+
+	claims, err := tokens.ParseAccessToken(response.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, recorded.TokenID, claims.ID)
+	actorID, ok, err := claims.ActorID()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 3, actorID)
+}
+
+func TestImpersonate_NotSupportUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockRepo := repositories.NewMockImpersonationRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewImpersonationService(mockRepo, mockUsers, newTestTokenService(), []int{3})
+
+	// Call the method
+	_, err := service.Impersonate(4, 42, "Ticket #1234", "")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrNotSupportUser)
+}
+
+func TestImpersonate_AdminTarget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockRepo := repositories.NewMockImpersonationRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewImpersonationService(mockRepo, mockUsers, newTestTokenService(), []int{3})
+
+	// Mock repository behavior
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Role: models.RoleAdmin}, nil)
+
+	// Call the method
+	_, err := service.Impersonate(3, 1, "Ticket #1234", "")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrCannotImpersonate)
+}
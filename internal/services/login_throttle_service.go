@@ -0,0 +1,94 @@
+package services
+
+import (
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"strings"
+	"time"
+)
+
+// Default policies. Client IPs get more room because many users may share one
+// address behind a NAT or proxy.
+var (
+	DefaultAccountThrottlePolicy = models.ThrottlePolicy{
+		FreeAttempts:    3,
+		MaxFailures:     10,
+		BaseDelay:       time.Second,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+	DefaultIPThrottlePolicy = models.ThrottlePolicy{
+		FreeAttempts:    20,
+		MaxFailures:     100,
+		BaseDelay:       time.Second,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+)
+
+// LoginThrottleService tracks failed logins per account and per client IP and
+// blocks further attempts with exponential backoff and temporary lockouts.
+type LoginThrottleService struct {
+	Repo          repositories.LoginAttemptRepositoryInterface
+	AccountPolicy models.ThrottlePolicy
+	IPPolicy      models.ThrottlePolicy
+}
+
+func NewLoginThrottleService(repo repositories.LoginAttemptRepositoryInterface) *LoginThrottleService {
+	return &LoginThrottleService{
+		Repo:          repo,
+		AccountPolicy: DefaultAccountThrottlePolicy,
+		IPPolicy:      DefaultIPThrottlePolicy,
+	}
+}
+
+// Attempt reserves a login attempt for the account from the IP and returns how
+// long the caller must wait when the attempt is refused. Zero means the
+// attempt may proceed. A reserved attempt counts as a failure until
+// RecordSuccess takes it back, so parallel guesses are throttled even before
+// any of them has been verified.
+func (s *LoginThrottleService) Attempt(email, ip string) (time.Duration, error) {
+	now := time.Now()
+	// The IP is reserved first so that a throttled client can't keep raising
+	// the counters of the accounts it targets
+	if wait, err := s.reserve(ipThrottleKey(ip), s.IPPolicy, now); err != nil || wait > 0 {
+		return wait, err
+	}
+	return s.reserve(accountThrottleKey(email), s.AccountPolicy, now)
+}
+
+// RecordSuccess clears the account's failures once the user is logged in. The
+// IP only gets its reserved attempt back so that one valid account can't be
+// used to reset it.
+func (s *LoginThrottleService) RecordSuccess(email, ip string) error {
+	if err := s.Repo.DeleteLoginAttempt(accountThrottleKey(email)); err != nil {
+		return err
+	}
+	return s.Repo.ReleaseLoginAttempt(ipThrottleKey(ip))
+}
+
+// Unlock clears the account's failures, lifting any lockout.
+func (s *LoginThrottleService) Unlock(email string) error {
+	return s.Repo.DeleteLoginAttempt(accountThrottleKey(email))
+}
+
+func (s *LoginThrottleService) reserve(key string, policy models.ThrottlePolicy, now time.Time) (time.Duration, error) {
+	attempt, reserved, err := s.Repo.ReserveLoginAttempt(key, now, policy)
+	if err != nil || reserved {
+		return 0, err
+	}
+	// The attempt was refused, so there is at least a nominal wait even if
+	// the block lifted in the meantime
+	if wait := policy.Wait(attempt, now); wait > 0 {
+		return wait, nil
+	}
+	return time.Second, nil
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottlePolicy_Delay(t *testing.T) {
+	policy := models.ThrottlePolicy{
+		FreeAttempts:    3,
+		MaxFailures:     6,
+		BaseDelay:       time.Second,
+		LockoutDuration: time.Minute,
+	}
+
+	tests := []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, time.Minute},
+		{100, time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.delay, policy.Delay(tt.failures), "failures=%d", tt.failures)
+	}
+}
+
+func TestLoginThrottle_LocksAccountAfterFailures(t *testing.T) {
+	service := NewLoginThrottleService(repositories.NewInMemoryLoginAttemptRepository())
+
+	for i := 0; i < service.AccountPolicy.FreeAttempts; i++ {
+		wait, err := service.Attempt("John@gmail.com", "10.0.0.1")
+		assert.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+
+	// The account is now throttled, regardless of case or client IP
+	wait, err := service.Attempt("john@gmail.com", "10.0.0.2")
+	assert.NoError(t, err)
+	assert.Greater(t, wait, time.Duration(0))
+
+	// Unlocking clears the account counter
+	assert.NoError(t, service.Unlock("john@gmail.com"))
+	wait, err = service.Attempt("john@gmail.com", "10.0.0.2")
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func TestLoginThrottle_ThrottlesIPAcrossAccounts(t *testing.T) {
+	service := NewLoginThrottleService(repositories.NewInMemoryLoginAttemptRepository())
+	service.IPPolicy.FreeAttempts = 2
+
+	_, err := service.Attempt("a@gmail.com", "10.0.0.1")
+	assert.NoError(t, err)
+	_, err = service.Attempt("b@gmail.com", "10.0.0.1")
+	assert.NoError(t, err)
+
+	wait, err := service.Attempt("c@gmail.com", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.Greater(t, wait, time.Duration(0))
+}
+
+func TestLoginThrottle_ConcurrentAttemptsAreCounted(t *testing.T) {
+	service := NewLoginThrottleService(repositories.NewInMemoryLoginAttemptRepository())
+
+	var mu sync.Mutex
+	allowed := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait, err := service.Attempt("john@gmail.com", "10.0.0.1")
+			assert.NoError(t, err)
+			if wait == 0 {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Only the free attempts get through before any of them is verified
+	assert.Equal(t, service.AccountPolicy.FreeAttempts, allowed)
+}
+
+func TestLoginThrottle_SuccessReleasesAttempt(t *testing.T) {
+	repo := repositories.NewInMemoryLoginAttemptRepository()
+	service := NewLoginThrottleService(repo)
+
+	for i := 0; i < 5; i++ {
+		wait, err := service.Attempt("john@gmail.com", "10.0.0.1")
+		assert.NoError(t, err)
+		assert.Zero(t, wait)
+		assert.NoError(t, service.RecordSuccess("john@gmail.com", "10.0.0.1"))
+	}
+
+	// Successful logins leave neither the account nor the IP counted
+	account, _ := repo.GetLoginAttempt(accountThrottleKey("john@gmail.com"))
+	ip, _ := repo.GetLoginAttempt(ipThrottleKey("10.0.0.1"))
+	assert.Zero(t, account.Failures)
+	assert.Zero(t, ip.Failures)
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"log"
+	"time"
+)
+
+const (
+	MagicLinkTTL              = 15 * time.Minute // How long a login link stays valid
+	DefaultMagicLinkInterval  = time.Minute      // Minimum time between two links for an account
+	DefaultMagicLinkHourlyCap = 5                // Maximum links per account and hour
+)
+
+var ErrInvalidMagicLink = errors.New("invalid or expired login link")
+
+// MagicLinkService signs users in without a password by emailing them a
+// single-use, short-lived login link.
+type MagicLinkService struct {
+	Tokens    repositories.ActionTokenRepositoryInterface
+	Users     repositories.UserRepositoryInterface
+	Mailer    Mailer
+	LoginURL  string        // Link sent to users; the token is appended as a query parameter
+	Interval  time.Duration // Minimum time between two links for an account
+	HourlyCap int           // Maximum links per account and hour
+}
+
+func NewMagicLinkService(tokens repositories.ActionTokenRepositoryInterface, users repositories.UserRepositoryInterface,
+	mailer Mailer, loginURL string) *MagicLinkService {
+	return &MagicLinkService{
+		Tokens:    tokens,
+		Users:     users,
+		Mailer:    mailer,
+		LoginURL:  loginURL,
+		Interval:  DefaultMagicLinkInterval,
+		HourlyCap: DefaultMagicLinkHourlyCap,
+	}
+}
+
+// SendLink emails a login link to the account with the given email. Unknown
+// emails and rate-limited accounts are ignored, so callers can't tell which
+// accounts exist or flood an inbox.
+func (s *MagicLinkService) SendLink(email string) error {
+	user, err := s.Users.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	// The limits are checked and the token created atomically, since
+	// concurrent requests are each sent in the background
+	created, err := s.Tokens.CreateRateLimitedActionToken(models.ActionToken{
+		UserID:    user.ID,
+		Purpose:   models.PurposeMagicLink,
+		TokenHash: utils.HashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(MagicLinkTTL),
+	}, s.Interval, s.HourlyCap)
+	if err != nil {
+		return err
+	}
+	if !created {
+		log.Printf("Magic link for user %d rate limited", user.ID)
+		return nil
+	}
+
+	body := fmt.Sprintf("Use the following token to sign in: %s\n\nIt expires in %s and can be used once.", token, MagicLinkTTL)
+	if s.LoginURL != "" {
+		link, err := linkWithToken(s.LoginURL, token)
+		if err != nil {
+			return err
+		}
+		body = fmt.Sprintf("Sign in using this link: %s\n\nIt expires in %s and can be used once.", link, MagicLinkTTL)
+	}
+	return s.Mailer.Send(user.Email, "Your sign-in link", body)
+}
+
+// Redeem consumes a login link and returns its user. Following the link
+// proves control of the mailbox, so the email is marked verified. Links sent
+// to an address the account no longer has are rejected.
+func (s *MagicLinkService) Redeem(token string) (models.User, error) {
+	stored, err := s.Tokens.GetActionTokenByHash(models.PurposeMagicLink, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repositories.ErrActionTokenNotFound) {
+			return models.User{}, ErrInvalidMagicLink
+		}
+		return models.User{}, err
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return models.User{}, ErrInvalidMagicLink
+	}
+
+	// A link can be redeemed once, even by concurrent requests
+	marked, err := s.Tokens.MarkActionTokenUsed(stored.ID)
+	if err != nil {
+		return models.User{}, err
+	}
+	if !marked {
+		return models.User{}, ErrInvalidMagicLink
+	}
+
+	user, err := s.Users.GetUserByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return models.User{}, ErrInvalidMagicLink
+		}
+		return models.User{}, err
+	}
+	if stored.Email == "" || stored.Email != user.Email {
+		return models.User{}, ErrInvalidMagicLink
+	}
+	if !user.EmailVerified {
+		if err := s.Users.MarkEmailVerified(user.ID, user.Email); err != nil {
+			return models.User{}, err
+		}
+		user.EmailVerified = true
+	}
+	return user, nil
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendLink_EmailsSingleUseLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	mailer := &fakeMailer{}
+	service := NewMagicLinkService(mockTokens, mockUsers, mailer, "https://example.com/login/magic/callback")
+
+	var stored models.ActionToken
+
+	// Mock repository behavior
+	mockUsers.EXPECT().GetUserByEmail("john@gmail.com").Return(models.User{ID: 1, Email: "john@gmail.com"}, nil)
+	mockTokens.EXPECT().CreateRateLimitedActionToken(gomock.Any(), DefaultMagicLinkInterval, DefaultMagicLinkHourlyCap).
+		DoAndReturn(func(token models.ActionToken, interval time.Duration, hourlyCap int) (bool, error) {
+			stored = token
+			return true, nil
+		})
+
+	// Call the method
+	err := service.SendLink("john@gmail.com")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mailer.sent)
+	assert.Equal(t, models.PurposeMagicLink, stored.Purpose)
+	assert.Equal(t, "john@gmail.com", stored.Email)
+	assert.WithinDuration(t, time.Now().Add(MagicLinkTTL), stored.ExpiresAt, time.Second)
+
+	_, after, found := strings.Cut(mailer.body, "?token=")
+	assert.True(t, found)
+	token, _, _ := strings.Cut(after, "\n")
+	assert.Equal(t, utils.HashToken(token), stored.TokenHash)
+}
+
+func TestSendLink_RateLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	mailer := &fakeMailer{}
+	service := NewMagicLinkService(mockTokens, mockUsers, mailer, "")
+
+	// Mock repository behavior: the account reached one of the limits
+	mockUsers.EXPECT().GetUserByEmail("john@gmail.com").Return(models.User{ID: 1}, nil)
+	mockTokens.EXPECT().CreateRateLimitedActionToken(gomock.Any(), DefaultMagicLinkInterval, DefaultMagicLinkHourlyCap).Return(false, nil)
+
+	// Call the method
+	err := service.SendLink("john@gmail.com")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 0, mailer.sent)
+}
+
+func TestSendLink_UnknownEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	mailer := &fakeMailer{}
+	service := NewMagicLinkService(nil, mockUsers, mailer, "")
+
+	// Mock repository behavior
+	mockUsers.EXPECT().GetUserByEmail("nobody@gmail.com").Return(models.User{}, repositories.ErrUserNotFound)
+
+	// Call the method
+	err := service.SendLink("nobody@gmail.com")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 0, mailer.sent)
+}
+
+func TestRedeem_SignsInAndVerifiesEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewMagicLinkService(mockTokens, mockUsers, &fakeMailer{}, "")
+
+	stored := models.ActionToken{ID: 4, UserID: 1, Email: "john@gmail.com", ExpiresAt: time.Now().Add(time.Minute)}
+
+	// Mock repository behavior
+	mockTokens.EXPECT().GetActionTokenByHash(models.PurposeMagicLink, utils.HashToken("link-token")).Return(stored, nil)
+	mockTokens.EXPECT().MarkActionTokenUsed(4).Return(true, nil)
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Email: "john@gmail.com", Role: models.RoleUser}, nil)
+	mockUsers.EXPECT().MarkEmailVerified(1, "john@gmail.com").Return(nil)
+
+	// Call the method
+	user, err := service.Redeem("link-token")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 1, user.ID)
+	assert.True(t, user.EmailVerified)
+}
+
+func TestRedeem_RejectsReplay(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	service := NewMagicLinkService(mockTokens, nil, &fakeMailer{}, "")
+
+	usedAt := time.Now().Add(-time.Second)
+
+	// Mock repository behavior: already used, then consumed by a concurrent request, then expired
+	mockTokens.EXPECT().GetActionTokenByHash(models.PurposeMagicLink, gomock.Any()).
+		Return(models.ActionToken{ID: 4, ExpiresAt: time.Now().Add(time.Minute), UsedAt: &usedAt}, nil)
+	mockTokens.EXPECT().GetActionTokenByHash(models.PurposeMagicLink, gomock.Any()).
+		Return(models.ActionToken{ID: 5, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+	mockTokens.EXPECT().MarkActionTokenUsed(5).Return(false, nil)
+	mockTokens.EXPECT().GetActionTokenByHash(models.PurposeMagicLink, gomock.Any()).
+		Return(models.ActionToken{ID: 6, ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+
+	for i := 0; i < 3; i++ {
+		// Call the method
+		_, err := service.Redeem("link-token")
+
+		// Assertions
+		assert.ErrorIs(t, err, ErrInvalidMagicLink)
+	}
+}
+
+func TestRedeem_RejectsLinkForPreviousEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewMagicLinkService(mockTokens, mockUsers, &fakeMailer{}, "")
+
+	stored := models.ActionToken{ID: 4, UserID: 1, Email: "john@gmail.com", ExpiresAt: time.Now().Add(time.Minute)}
+
+	// Mock repository behavior: the account's address changed after the link was sent
+	mockTokens.EXPECT().GetActionTokenByHash(models.PurposeMagicLink, gomock.Any()).Return(stored, nil)
+	mockTokens.EXPECT().MarkActionTokenUsed(4).Return(true, nil)
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Email: "jane@gmail.com"}, nil)
+
+	// Call the method
+	_, err := service.Redeem("link-token")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrInvalidMagicLink)
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"log"
+	"net/url"
+)
+
+// Mailer delivers email messages. Implementations can wrap SMTP or a
+// transactional email provider.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailer writes messages to the log instead of sending them. It is meant
+// for local development.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(to, subject, body string) error {
+	log.Printf("Mailer: to=%s subject=%q body=%q", to, subject, body)
+	return nil
+}
+
+// linkWithToken adds the token to the query of the link base URL, keeping any
+// query parameters it already has.
+func linkWithToken(base, token string) (string, error) {
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
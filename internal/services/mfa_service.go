@@ -0,0 +1,194 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"strings"
+	"time"
+)
+
+// recoveryCodeCount is the number of recovery codes issued on enrollment.
+const recoveryCodeCount = 10
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrInvalidMFACode    = errors.New("invalid authentication code")
+)
+
+// MFAService manages TOTP enrollment and verifies second-factor codes.
+type MFAService struct {
+	Repo   repositories.MFARepositoryInterface
+	Issuer string // Shown in authenticator apps next to the account name
+}
+
+func NewMFAService(repo repositories.MFARepositoryInterface, issuer string) *MFAService {
+	return &MFAService{Repo: repo, Issuer: issuer}
+}
+
+// IsEnabled reports whether the user must provide a second factor on login.
+func (s *MFAService) IsEnabled(userID int) (bool, error) {
+	settings, err := s.Repo.GetTOTP(userID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTOTPNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return settings.Enabled, nil
+}
+
+// BeginEnrollment generates a new TOTP secret for the user and returns it with
+// its otpauth:// URI. The secret is inactive until ConfirmEnrollment succeeds.
+func (s *MFAService) BeginEnrollment(user models.User) (string, string, error) {
+	enabled, err := s.IsEnabled(user.ID)
+	if err != nil {
+		return "", "", err
+	}
+	if enabled {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.Repo.SaveTOTPSecret(user.ID, secret); err != nil {
+		return "", "", err
+	}
+	return secret, utils.TOTPURI(s.Issuer, user.Email, secret), nil
+}
+
+// ConfirmEnrollment enables TOTP once the user proves their authenticator
+// works, and returns freshly generated recovery codes.
+func (s *MFAService) ConfirmEnrollment(userID int, code string) ([]string, error) {
+	settings, err := s.Repo.GetTOTP(userID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTOTPNotFound) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	if settings.Enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := utils.ValidateTOTP(settings.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	if err := s.Repo.EnableTOTP(userID, step); err != nil {
+		return nil, err
+	}
+	return s.RegenerateRecoveryCodes(userID)
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes. Only hashes are stored.
+func (s *MFAService) RegenerateRecoveryCodes(userID int) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := utils.HashPassword(code)
+		if err != nil {
+			return nil, err
+		}
+		codes[i], hashes[i] = code, hash
+	}
+
+	if err := s.Repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify checks a TOTP code or, failing that, a recovery code. Each TOTP code
+// and recovery code is accepted at most once.
+func (s *MFAService) Verify(userID int, code string) error {
+	settings, err := s.Repo.GetTOTP(userID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTOTPNotFound) {
+			return ErrMFANotEnrolled
+		}
+		return err
+	}
+	if !settings.Enabled {
+		return ErrMFANotEnrolled
+	}
+
+	if step, ok := utils.ValidateTOTP(settings.Secret, code, time.Now()); ok {
+		if step <= settings.LastUsedStep {
+			return ErrInvalidMFACode
+		}
+		accepted, err := s.Repo.UpdateTOTPLastUsedStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	return s.useRecoveryCode(userID, code)
+}
+
+// Disable removes TOTP after verifying a current code or recovery code.
+func (s *MFAService) Disable(userID int, code string) error {
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+	return s.Repo.DeleteTOTP(userID)
+}
+
+func (s *MFAService) useRecoveryCode(userID int, code string) error {
+	code = normalizeRecoveryCode(code)
+	if code == "" {
+		return ErrInvalidMFACode
+	}
+
+	codes, err := s.Repo.GetUnusedRecoveryCodes(userID)
+	if err != nil {
+		return err
+	}
+	for _, stored := range codes {
+		if utils.VerifyPassword(stored.CodeHash, code) != nil {
+			continue
+		}
+		marked, err := s.Repo.MarkRecoveryCodeUsed(stored.ID)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+	return ErrInvalidMFACode
+}
+
+// generateRecoveryCode returns a code such as "abcde-fghij".
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode accepts codes typed without the dash or in upper case.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) != 10 {
+		return ""
+	}
+	return code[:5] + "-" + code[5:]
+}
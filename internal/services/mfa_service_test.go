@@ -0,0 +1,85 @@
+package services
+
+import (
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirmEnrollment_ReturnsRecoveryCodes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockMFARepositoryInterface(ctrl)
+	service := NewMFAService(mockRepo, "go-crud")
+
+	secret, _ := utils.GenerateTOTPSecret()
+	step := utils.TOTPStep(time.Now())
+	code, _ := utils.TOTPCode(secret, step)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetTOTP(1).Return(models.TOTPSettings{UserID: 1, Secret: secret}, nil)
+	mockRepo.EXPECT().EnableTOTP(1, step).Return(nil)
+	mockRepo.EXPECT().ReplaceRecoveryCodes(1, gomock.Any()).DoAndReturn(func(userID int, hashes []string) error {
+		assert.Len(t, hashes, recoveryCodeCount)
+		return nil
+	})
+
+	// Call the method
+	codes, err := service.ConfirmEnrollment(1, code)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+}
+
+func TestVerify_RejectsReplayedCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockMFARepositoryInterface(ctrl)
+	service := NewMFAService(mockRepo, "go-crud")
+
+	secret, _ := utils.GenerateTOTPSecret()
+	step := utils.TOTPStep(time.Now())
+	code, _ := utils.TOTPCode(secret, step)
+
+	// Mock repository behavior: the code for this step was already accepted
+	mockRepo.EXPECT().GetTOTP(1).Return(models.TOTPSettings{UserID: 1, Secret: secret, Enabled: true, LastUsedStep: step}, nil)
+
+	// Call the method
+	err := service.Verify(1, code)
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+}
+
+func TestVerify_AcceptsRecoveryCode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockMFARepositoryInterface(ctrl)
+	service := NewMFAService(mockRepo, "go-crud")
+
+	secret, _ := utils.GenerateTOTPSecret()
+	codeHash, _ := utils.HashPassword("abcde-fghij")
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetTOTP(1).Return(models.TOTPSettings{UserID: 1, Secret: secret, Enabled: true}, nil)
+	mockRepo.EXPECT().GetUnusedRecoveryCodes(1).Return([]models.RecoveryCode{{ID: 5, UserID: 1, CodeHash: codeHash}}, nil)
+	mockRepo.EXPECT().MarkRecoveryCodeUsed(5).Return(true, nil)
+
+	// Call the method: recovery codes are accepted without the dash
+	err := service.Verify(1, "ABCDEFGHIJ")
+
+	// Assertions
+	assert.NoError(t, err)
+}
@@ -0,0 +1,503 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuthorizationCodeTTL is how long a code from /oauth/authorize can be exchanged.
+const AuthorizationCodeTTL = 10 * time.Minute
+
+// OAuthError is an error response defined by RFC 6749, such as invalid_grant.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+var (
+	ErrInvalidClient         = oauthError("invalid_client", "client authentication failed")
+	ErrUserDeleted           = oauthError("invalid_grant", "the user has been deleted")
+	ErrConsentRequired       = oauthError("consent_required", "the user has not approved the requested scope for this client")
+	ErrInvalidClientMetadata = errors.New("invalid client metadata")
+)
+
+// AuthorizeRequest holds the parameters of an authorization request.
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// OAuthService implements an OAuth 2.0 authorization server: the
+// authorization code grant with PKCE, the refresh token grant, the client
+// credentials grant, token introspection (RFC 7662) and revocation (RFC 7009).
+type OAuthService struct {
+	Repo        repositories.OAuthRepositoryInterface
+	Users       repositories.UserRepositoryInterface
+	Tokens      *utils.TokenService
+	Revocations *RevocationService
+}
+
+func NewOAuthService(repo repositories.OAuthRepositoryInterface, users repositories.UserRepositoryInterface,
+	tokens *utils.TokenService, revocations *RevocationService) *OAuthService {
+	return &OAuthService{Repo: repo, Users: users, Tokens: tokens, Revocations: revocations}
+}
+
+// CreateClient registers a client and returns it with its secret, which can't
+// be recovered later. Public clients get no secret.
+func (s *OAuthService) CreateClient(req models.CreateOAuthClientRequest) (models.OAuthClient, string, error) {
+	if slices.Contains(req.GrantTypes, models.GrantAuthorizationCode) && len(req.RedirectURIs) == 0 {
+		return models.OAuthClient{}, "", fmt.Errorf("%w: authorization_code requires at least one redirect URI", ErrInvalidClientMetadata)
+	}
+	if slices.Contains(req.GrantTypes, models.GrantRefreshToken) && !slices.Contains(req.GrantTypes, models.GrantAuthorizationCode) {
+		return models.OAuthClient{}, "", fmt.Errorf("%w: refresh_token requires authorization_code", ErrInvalidClientMetadata)
+	}
+	if slices.Contains(req.GrantTypes, models.GrantClientCredentials) {
+		if !req.Confidential || req.ServiceUserID == nil {
+			return models.OAuthClient{}, "", fmt.Errorf("%w: client_credentials requires a confidential client with a service user", ErrInvalidClientMetadata)
+		}
+		if _, err := s.Users.GetUserByID(*req.ServiceUserID); err != nil {
+			if errors.Is(err, repositories.ErrUserNotFound) {
+				return models.OAuthClient{}, "", fmt.Errorf("%w: service user not found", ErrInvalidClientMetadata)
+			}
+			return models.OAuthClient{}, "", err
+		}
+	}
+
+	clientID, err := generateClientID()
+	if err != nil {
+		return models.OAuthClient{}, "", err
+	}
+	client := models.OAuthClient{
+		ClientID:      clientID,
+		Name:          req.Name,
+		RedirectURIs:  req.RedirectURIs,
+		GrantTypes:    req.GrantTypes,
+		Scopes:        req.Scopes,
+		ServiceUserID: req.ServiceUserID,
+	}
+
+	var secret string
+	if req.Confidential {
+		if secret, err = utils.GenerateOpaqueToken(); err != nil {
+			return models.OAuthClient{}, "", err
+		}
+		client.SecretHash = utils.HashToken(secret)
+	}
+
+	client, err = s.Repo.CreateClient(client)
+	if err != nil {
+		return models.OAuthClient{}, "", err
+	}
+	return client, secret, nil
+}
+
+func (s *OAuthService) ListClients() ([]models.OAuthClient, error) {
+	return s.Repo.GetClients()
+}
+
+func (s *OAuthService) DeleteClient(clientID string) error {
+	return s.Repo.DeleteClient(clientID)
+}
+
+// ResolveRedirectURI returns the client and the redirect URI to use for an
+// authorization request. Errors from here must be shown to the user rather
+// than sent to the redirect URI, which isn't trusted yet.
+func (s *OAuthService) ResolveRedirectURI(clientID, redirectURI string) (models.OAuthClient, string, error) {
+	client, err := s.Repo.GetClient(clientID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrOAuthClientNotFound) {
+			return models.OAuthClient{}, "", oauthError("invalid_request", "unknown client_id")
+		}
+		return models.OAuthClient{}, "", err
+	}
+
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		return client, client.RedirectURIs[0], nil
+	}
+	// Redirect URIs must match a registered URI exactly
+	if redirectURI == "" || !slices.Contains(client.RedirectURIs, redirectURI) {
+		return models.OAuthClient{}, "", oauthError("invalid_request", "redirect_uri is not registered for this client")
+	}
+	return client, redirectURI, nil
+}
+
+// Authorize issues an authorization code for the user to the client, bound to
+// the redirect URI and the PKCE code challenge. It fails with
+// ErrConsentRequired unless the user approved the scope with GrantConsent.
+func (s *OAuthService) Authorize(userID int, client models.OAuthClient, redirectURI string, req AuthorizeRequest) (string, error) {
+	if req.ResponseType != "code" {
+		return "", oauthError("unsupported_response_type", "only response_type=code is supported")
+	}
+	if !slices.Contains(client.GrantTypes, models.GrantAuthorizationCode) {
+		return "", oauthError("unauthorized_client", "client may not use the authorization code grant")
+	}
+	// PKCE is required for every client, and only S256 is accepted
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return "", oauthError("invalid_request", "code_challenge with code_challenge_method=S256 is required")
+	}
+	if len(req.CodeChallenge) != 43 {
+		return "", oauthError("invalid_request", "invalid code_challenge")
+	}
+	scope, err := resolveScope(client.Scopes, req.Scope)
+	if err != nil {
+		return "", err
+	}
+	consented, err := s.Repo.GetConsent(userID, client.ClientID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrOAuthConsentNotFound) {
+			return "", ErrConsentRequired
+		}
+		return "", err
+	}
+	if !scopeCovers(consented, scope) {
+		return "", ErrConsentRequired
+	}
+
+	code, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.Repo.CreateAuthorizationCode(models.AuthorizationCode{
+		CodeHash:            utils.HashToken(code),
+		ClientID:            client.ClientID,
+		UserID:              userID,
+		RedirectURI:         redirectURI,
+		RedirectURIProvided: req.RedirectURI != "",
+		Scope:               scope,
+		CodeChallenge:       req.CodeChallenge,
+		ExpiresAt:           time.Now().Add(AuthorizationCodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// GrantConsent records that the user approved the scope for the client, in
+// addition to the scopes approved before.
+func (s *OAuthService) GrantConsent(userID int, client models.OAuthClient, scope string) error {
+	scope, err := resolveScope(client.Scopes, scope)
+	if err != nil {
+		return err
+	}
+	consented, err := s.Repo.GetConsent(userID, client.ClientID)
+	if err != nil && !errors.Is(err, repositories.ErrOAuthConsentNotFound) {
+		return err
+	}
+	granted := strings.Fields(consented)
+	for _, requested := range strings.Fields(scope) {
+		if !slices.Contains(granted, requested) {
+			granted = append(granted, requested)
+		}
+	}
+	return s.Repo.SaveConsent(userID, client.ClientID, strings.Join(granted, " "))
+}
+
+// AuthenticateClient checks the client's credentials. Public clients identify
+// themselves with their client ID only.
+func (s *OAuthService) AuthenticateClient(clientID, secret string) (models.OAuthClient, error) {
+	if clientID == "" {
+		return models.OAuthClient{}, ErrInvalidClient
+	}
+	client, err := s.Repo.GetClient(clientID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrOAuthClientNotFound) {
+			return models.OAuthClient{}, ErrInvalidClient
+		}
+		return models.OAuthClient{}, err
+	}
+
+	if !client.Confidential() {
+		if secret != "" {
+			return models.OAuthClient{}, ErrInvalidClient
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(client.SecretHash), []byte(utils.HashToken(secret))) != 1 {
+		return models.OAuthClient{}, ErrInvalidClient
+	}
+	return client, nil
+}
+
+// ExchangeAuthorizationCode redeems an authorization code for tokens after
+// checking the redirect URI and the PKCE code verifier.
+func (s *OAuthService) ExchangeAuthorizationCode(client models.OAuthClient, code, redirectURI, codeVerifier string) (models.OAuthTokenResponse, error) {
+	if !slices.Contains(client.GrantTypes, models.GrantAuthorizationCode) {
+		return models.OAuthTokenResponse{}, oauthError("unauthorized_client", "client may not use the authorization code grant")
+	}
+
+	stored, err := s.Repo.ConsumeAuthorizationCode(utils.HashToken(code))
+	if err != nil {
+		if errors.Is(err, repositories.ErrAuthorizationCodeNotFound) {
+			return models.OAuthTokenResponse{}, oauthError("invalid_grant", "invalid or used authorization code")
+		}
+		return models.OAuthTokenResponse{}, err
+	}
+	// redirect_uri must be repeated if the authorization request included it
+	redirectMismatch := redirectURI != stored.RedirectURI && (stored.RedirectURIProvided || redirectURI != "")
+	if stored.ClientID != client.ClientID || time.Now().After(stored.ExpiresAt) || redirectMismatch {
+		return models.OAuthTokenResponse{}, oauthError("invalid_grant", "invalid or used authorization code")
+	}
+	if !verifyCodeChallenge(codeVerifier, stored.CodeChallenge) {
+		return models.OAuthTokenResponse{}, oauthError("invalid_grant", "code_verifier does not match the code challenge")
+	}
+
+	user, err := s.Users.GetUserByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return models.OAuthTokenResponse{}, ErrUserDeleted
+		}
+		return models.OAuthTokenResponse{}, err
+	}
+	return s.issue(client, user, stored.Scope, slices.Contains(client.GrantTypes, models.GrantRefreshToken))
+}
+
+// RefreshToken rotates an OAuth refresh token. The scope may be narrowed but
+// not widened. Presenting a refresh token that was already rotated revokes
+// every refresh token the client holds for the user.
+func (s *OAuthService) RefreshToken(client models.OAuthClient, refreshToken, scope string) (models.OAuthTokenResponse, error) {
+	if !slices.Contains(client.GrantTypes, models.GrantRefreshToken) {
+		return models.OAuthTokenResponse{}, oauthError("unauthorized_client", "client may not use the refresh token grant")
+	}
+
+	stored, err := s.Repo.GetOAuthRefreshToken(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repositories.ErrOAuthRefreshTokenNotFound) {
+			return models.OAuthTokenResponse{}, oauthError("invalid_grant", "invalid refresh token")
+		}
+		return models.OAuthTokenResponse{}, err
+	}
+	if stored.ClientID != client.ClientID || time.Now().After(stored.ExpiresAt) {
+		return models.OAuthTokenResponse{}, oauthError("invalid_grant", "invalid refresh token")
+	}
+
+	if stored.RevokedAt != nil {
+		return models.OAuthTokenResponse{}, s.revokeOnReuse(client.ClientID, stored.UserID)
+	}
+	marked, err := s.Repo.RevokeOAuthRefreshToken(stored.ID)
+	if err != nil {
+		return models.OAuthTokenResponse{}, err
+	}
+	if !marked {
+		// Another request rotated the token between our read and update.
+		return models.OAuthTokenResponse{}, s.revokeOnReuse(client.ClientID, stored.UserID)
+	}
+
+	if scope != "" {
+		if scope, err = resolveScope(strings.Fields(stored.Scope), scope); err != nil {
+			return models.OAuthTokenResponse{}, err
+		}
+	} else {
+		scope = stored.Scope
+	}
+
+	user, err := s.Users.GetUserByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return models.OAuthTokenResponse{}, ErrUserDeleted
+		}
+		return models.OAuthTokenResponse{}, err
+	}
+	return s.issue(client, user, scope, true)
+}
+
+// ClientCredentials issues an access token to a confidential client acting as
+// its service user. No refresh token is issued (RFC 6749 section 4.4.3).
+func (s *OAuthService) ClientCredentials(client models.OAuthClient, scope string) (models.OAuthTokenResponse, error) {
+	if !slices.Contains(client.GrantTypes, models.GrantClientCredentials) || !client.Confidential() || client.ServiceUserID == nil {
+		return models.OAuthTokenResponse{}, oauthError("unauthorized_client", "client may not use the client credentials grant")
+	}
+	scope, err := resolveScope(client.Scopes, scope)
+	if err != nil {
+		return models.OAuthTokenResponse{}, err
+	}
+
+	user, err := s.Users.GetUserByID(*client.ServiceUserID)
+	if err != nil {
+		return models.OAuthTokenResponse{}, err
+	}
+	return s.issue(client, user, scope, false)
+}
+
+// Introspect describes a token to a confidential client (RFC 7662). Refresh
+// tokens are only described to the client they were issued to.
+func (s *OAuthService) Introspect(client models.OAuthClient, token string) (models.IntrospectionResponse, error) {
+	if !client.Confidential() {
+		return models.IntrospectionResponse{}, ErrInvalidClient
+	}
+
+	if claims, err := s.Tokens.ParseAccessToken(token); err == nil {
+		userID, _ := claims.UserID()
+		revoked, err := s.Revocations.IsRevoked(claims.ID, userID, claims.SessionID, claims.IssuedAt.Time)
+		if err != nil {
+			return models.IntrospectionResponse{}, err
+		}
+		if revoked {
+			return models.IntrospectionResponse{Active: false}, nil
+		}
+		return models.IntrospectionResponse{
+			Active:    true,
+			Scope:     claims.Scope,
+			ClientID:  claims.ClientID,
+			Subject:   claims.Subject,
+			TokenType: "Bearer",
+			ExpiresAt: claims.ExpiresAt.Unix(),
+			IssuedAt:  claims.IssuedAt.Unix(),
+		}, nil
+	}
+
+	stored, err := s.Repo.GetOAuthRefreshToken(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repositories.ErrOAuthRefreshTokenNotFound) {
+			return models.IntrospectionResponse{Active: false}, nil
+		}
+		return models.IntrospectionResponse{}, err
+	}
+	if stored.ClientID != client.ClientID || stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return models.IntrospectionResponse{Active: false}, nil
+	}
+	return models.IntrospectionResponse{
+		Active:    true,
+		Scope:     stored.Scope,
+		ClientID:  stored.ClientID,
+		Subject:   strconv.Itoa(stored.UserID),
+		TokenType: "refresh_token",
+		ExpiresAt: stored.ExpiresAt.Unix(),
+		IssuedAt:  stored.CreatedAt.Unix(),
+	}, nil
+}
+
+// Revoke revokes a refresh token or access token issued to the client (RFC
+// 7009). Unknown tokens and tokens of other clients are silently ignored.
+func (s *OAuthService) Revoke(client models.OAuthClient, token string) error {
+	stored, err := s.Repo.GetOAuthRefreshToken(utils.HashToken(token))
+	if err == nil {
+		if stored.ClientID != client.ClientID {
+			return nil
+		}
+		_, err := s.Repo.RevokeOAuthRefreshToken(stored.ID)
+		return err
+	}
+	if !errors.Is(err, repositories.ErrOAuthRefreshTokenNotFound) {
+		return err
+	}
+
+	claims, err := s.Tokens.ParseAccessToken(token)
+	if err != nil || claims.ClientID != client.ClientID {
+		return nil
+	}
+	return s.Revocations.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+}
+
+func (s *OAuthService) revokeOnReuse(clientID string, userID int) error {
+	if err := s.Repo.RevokeOAuthRefreshTokens(clientID, userID); err != nil {
+		return err
+	}
+	return oauthError("invalid_grant", "refresh token reuse detected")
+}
+
+func (s *OAuthService) issue(client models.OAuthClient, user models.User, scope string, withRefreshToken bool) (models.OAuthTokenResponse, error) {
+	accessToken, err := s.Tokens.IssueOAuthAccessToken(user.ID, user.Role, client.ClientID, scope)
+	if err != nil {
+		return models.OAuthTokenResponse{}, err
+	}
+	response := models.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.Tokens.AccessTokenTTL.Seconds()),
+		Scope:       scope,
+	}
+	if !withRefreshToken {
+		return response, nil
+	}
+
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return models.OAuthTokenResponse{}, err
+	}
+	err = s.Repo.CreateOAuthRefreshToken(models.OAuthRefreshToken{
+		TokenHash: utils.HashToken(refreshToken),
+		ClientID:  client.ClientID,
+		UserID:    user.ID,
+		Scope:     scope,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+	})
+	if err != nil {
+		return models.OAuthTokenResponse{}, err
+	}
+	response.RefreshToken = refreshToken
+	return response, nil
+}
+
+// resolveScope checks that every requested scope is allowed. An empty request
+// grants every allowed scope.
+func resolveScope(allowed []string, requested string) (string, error) {
+	if requested == "" {
+		return strings.Join(allowed, " "), nil
+	}
+
+	var granted []string
+	for _, scope := range strings.Fields(requested) {
+		if !slices.Contains(allowed, scope) {
+			return "", oauthError("invalid_scope", "scope "+scope+" is not allowed")
+		}
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	return strings.Join(granted, " "), nil
+}
+
+// scopeCovers reports whether every scope in requested is in granted.
+func scopeCovers(granted, requested string) bool {
+	approved := strings.Fields(granted)
+	for _, scope := range strings.Fields(requested) {
+		if !slices.Contains(approved, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyCodeChallenge checks a PKCE code verifier against an S256 challenge (RFC 7636).
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+func generateClientID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
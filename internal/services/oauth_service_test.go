@@ -0,0 +1,312 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+func testCodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func testOAuthClient() models.OAuthClient {
+	return models.OAuthClient{
+		ClientID:     "web-app",
+		RedirectURIs: []string{"https://app.example.com/callback"},
+		GrantTypes:   []string{models.GrantAuthorizationCode, models.GrantRefreshToken},
+		Scopes:       []string{models.ScopeUsersRead, models.ScopeUsersWrite},
+	}
+}
+
+func TestOAuth_AuthorizationCodeWithPKCE(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockRepo := repositories.NewMockOAuthRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewOAuthService(mockRepo, mockUsers, newTestTokenService(), nil)
+	client := testOAuthClient()
+
+	var stored models.AuthorizationCode
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetConsent(1, "web-app").Return(models.ScopeUsersRead, nil)
+	mockRepo.EXPECT().CreateAuthorizationCode(gomock.Any()).DoAndReturn(func(code models.AuthorizationCode) error {
+		stored = code
+		return nil
+	})
+
+	// Call the method
+	code, err := service.Authorize(1, client, client.RedirectURIs[0], AuthorizeRequest{
+		ResponseType:        "code",
+		RedirectURI:         client.RedirectURIs[0],
+		Scope:               models.ScopeUsersRead,
+		CodeChallenge:       testCodeChallenge(testCodeVerifier),
+		CodeChallengeMethod: "S256",
+	})
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, utils.HashToken(code), stored.CodeHash)
+	assert.Equal(t, models.ScopeUsersRead, stored.Scope)
+	assert.True(t, stored.RedirectURIProvided)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().ConsumeAuthorizationCode(stored.CodeHash).Return(stored, nil)
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Role: models.RoleUser}, nil)
+	mockRepo.EXPECT().CreateOAuthRefreshToken(gomock.Any()).Return(nil)
+
+	// Call the method
+	tokens, err := service.ExchangeAuthorizationCode(client, code, client.RedirectURIs[0], testCodeVerifier)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, models.ScopeUsersRead, tokens.Scope)
+
+	claims, err := service.Tokens.ParseAccessToken(tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "web-app", claims.ClientID)
+	assert.Equal(t, models.ScopeUsersRead, claims.Scope)
+}
+
+func TestOAuth_AuthorizeRequiresPKCE(t *testing.T) {
+	service := NewOAuthService(nil, nil, nil, nil)
+	client := testOAuthClient()
+
+	_, err := service.Authorize(1, client, client.RedirectURIs[0], AuthorizeRequest{ResponseType: "code"})
+	assert.ErrorContains(t, err, "invalid_request")
+
+	_, err = service.Authorize(1, client, client.RedirectURIs[0], AuthorizeRequest{
+		ResponseType:        "code",
+		CodeChallenge:       testCodeVerifier,
+		CodeChallengeMethod: "plain",
+	})
+	assert.ErrorContains(t, err, "invalid_request")
+}
+
+func TestOAuth_AuthorizeRequiresConsent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockOAuthRepositoryInterface(ctrl)
+	service := NewOAuthService(mockRepo, nil, newTestTokenService(), nil)
+	client := testOAuthClient()
+	req := AuthorizeRequest{
+		ResponseType:        "code",
+		Scope:               models.ScopeUsersWrite,
+		CodeChallenge:       testCodeChallenge(testCodeVerifier),
+		CodeChallengeMethod: "S256",
+	}
+
+	// Mock repository behavior: never approved, then approved for another scope only
+	mockRepo.EXPECT().GetConsent(1, "web-app").Return("", repositories.ErrOAuthConsentNotFound)
+	mockRepo.EXPECT().GetConsent(1, "web-app").Return(models.ScopeUsersRead, nil)
+
+	// Call the method
+	_, neverErr := service.Authorize(1, client, client.RedirectURIs[0], req)
+	_, narrowerErr := service.Authorize(1, client, client.RedirectURIs[0], req)
+
+	// Assertions
+	assert.ErrorIs(t, neverErr, ErrConsentRequired)
+	assert.ErrorIs(t, narrowerErr, ErrConsentRequired)
+}
+
+func TestOAuth_GrantConsentAddsScopes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockOAuthRepositoryInterface(ctrl)
+	service := NewOAuthService(mockRepo, nil, newTestTokenService(), nil)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetConsent(1, "web-app").Return(models.ScopeUsersRead, nil)
+	mockRepo.EXPECT().SaveConsent(1, "web-app", models.ScopeUsersRead+" "+models.ScopeUsersWrite).Return(nil)
+
+	// Call the method
+	err := service.GrantConsent(1, testOAuthClient(), models.ScopeUsersWrite)
+
+	// Assertions
+	assert.NoError(t, err)
+}
+
+func TestOAuth_ExchangeRedirectURIOnlyRequiredWhenSent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockRepo := repositories.NewMockOAuthRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewOAuthService(mockRepo, mockUsers, newTestTokenService(), nil)
+	client := testOAuthClient()
+	client.GrantTypes = []string{models.GrantAuthorizationCode}
+
+	stored := models.AuthorizationCode{
+		ClientID:      "web-app",
+		UserID:        1,
+		RedirectURI:   client.RedirectURIs[0],
+		CodeChallenge: testCodeChallenge(testCodeVerifier),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+
+	// Mock repository behavior: the authorization request omitted redirect_uri, then included it
+	mockRepo.EXPECT().ConsumeAuthorizationCode(utils.HashToken("code")).Return(stored, nil)
+	mockUsers.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Role: models.RoleUser}, nil)
+	stored.RedirectURIProvided = true
+	mockRepo.EXPECT().ConsumeAuthorizationCode(utils.HashToken("code")).Return(stored, nil)
+
+	// Call the method
+	_, omittedErr := service.ExchangeAuthorizationCode(client, "code", "", testCodeVerifier)
+	_, missingErr := service.ExchangeAuthorizationCode(client, "code", "", testCodeVerifier)
+
+	// Assertions
+	assert.NoError(t, omittedErr)
+	assert.ErrorContains(t, missingErr, "invalid_grant")
+}
+
+func TestOAuth_ExchangeRejectsWrongVerifier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockOAuthRepositoryInterface(ctrl)
+	service := NewOAuthService(mockRepo, nil, newTestTokenService(), nil)
+	client := testOAuthClient()
+
+	// Mock repository behavior
+	mockRepo.EXPECT().ConsumeAuthorizationCode(utils.HashToken("code")).Return(models.AuthorizationCode{
+		ClientID:      "web-app",
+		UserID:        1,
+		RedirectURI:   client.RedirectURIs[0],
+		CodeChallenge: testCodeChallenge(testCodeVerifier),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}, nil)
+
+	// Call the method
+	_, err := service.ExchangeAuthorizationCode(client, "code", client.RedirectURIs[0], strings.Repeat("x", 43))
+
+	// Assertions
+	assert.ErrorContains(t, err, "invalid_grant")
+}
+
+func TestOAuth_ResolveRedirectURIRequiresExactMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockOAuthRepositoryInterface(ctrl)
+	service := NewOAuthService(mockRepo, nil, nil, nil)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetClient("web-app").Return(testOAuthClient(), nil).Times(2)
+
+	// Call the method
+	_, redirectURI, err := service.ResolveRedirectURI("web-app", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://app.example.com/callback", redirectURI)
+
+	_, _, err = service.ResolveRedirectURI("web-app", "https://app.example.com/callback/../evil")
+	assert.Error(t, err)
+}
+
+func TestOAuth_RefreshTokenReuseRevokesTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockOAuthRepositoryInterface(ctrl)
+	service := NewOAuthService(mockRepo, nil, newTestTokenService(), nil)
+
+	revokedAt := time.Now().Add(-time.Minute)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetOAuthRefreshToken(utils.HashToken("old-token")).Return(models.OAuthRefreshToken{
+		ID:        5,
+		ClientID:  "web-app",
+		UserID:    1,
+		ExpiresAt: time.Now().Add(time.Hour),
+		RevokedAt: &revokedAt,
+	}, nil)
+	mockRepo.EXPECT().RevokeOAuthRefreshTokens("web-app", 1).Return(nil)
+
+	// Call the method
+	_, err := service.RefreshToken(testOAuthClient(), "old-token", "")
+
+	// Assertions
+	assert.ErrorContains(t, err, "refresh token reuse detected")
+}
+
+func TestOAuth_ClientCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewOAuthService(nil, mockUsers, newTestTokenService(), nil)
+
+	serviceUserID := 9
+	client := models.OAuthClient{
+		ClientID:      "batch-job",
+		SecretHash:    utils.HashToken("secret"),
+		GrantTypes:    []string{models.GrantClientCredentials},
+		Scopes:        []string{models.ScopeUsersRead},
+		ServiceUserID: &serviceUserID,
+	}
+
+	// Scopes outside the client's registration are rejected
+	_, err := service.ClientCredentials(client, models.ScopeUsersWrite)
+	assert.ErrorContains(t, err, "invalid_scope")
+
+	// Mock repository behavior
+	mockUsers.EXPECT().GetUserByID(9).Return(models.User{ID: 9, Role: models.RoleUser}, nil)
+
+	// Call the method
+	tokens, err := service.ClientCredentials(client, "")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Empty(t, tokens.RefreshToken)
+	assert.Equal(t, models.ScopeUsersRead, tokens.Scope)
+}
+
+func TestOAuth_AuthenticateClient(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockOAuthRepositoryInterface(ctrl)
+	service := NewOAuthService(mockRepo, nil, nil, nil)
+
+	confidential := models.OAuthClient{ClientID: "batch-job", SecretHash: utils.HashToken("secret")}
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetClient("batch-job").Return(confidential, nil).Times(2)
+	mockRepo.EXPECT().GetClient("web-app").Return(testOAuthClient(), nil).Times(2)
+
+	_, err := service.AuthenticateClient("batch-job", "secret")
+	assert.NoError(t, err)
+	_, err = service.AuthenticateClient("batch-job", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidClient)
+
+	// Public clients must not present a secret
+	_, err = service.AuthenticateClient("web-app", "")
+	assert.NoError(t, err)
+	_, err = service.AuthenticateClient("web-app", "secret")
+	assert.ErrorIs(t, err, ErrInvalidClient)
+}
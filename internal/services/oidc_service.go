@@ -0,0 +1,328 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"strings"
+	"time"
+)
+
+const (
+	OIDCLoginStateTTL  = 10 * time.Minute // How long a user has to complete a login at the provider
+	OIDCPendingLinkTTL = 10 * time.Minute // How long a user has to confirm linking an existing account
+)
+
+var (
+	ErrUnknownOIDCProvider     = errors.New("unknown identity provider")
+	ErrInvalidOIDCState        = errors.New("invalid or expired login state")
+	ErrOIDCEmailNotVerified    = errors.New("identity provider did not verify the email address")
+	ErrOIDCAccountNotFound     = errors.New("no account is linked to this identity")
+	ErrOIDCAccountNotVerified  = errors.New("verify the email address of the existing account before linking it")
+	ErrInvalidOIDCLink         = errors.New("invalid or expired account link")
+	ErrInvalidOIDCLinkPassword = errors.New("incorrect password for the existing account")
+)
+
+// ErrOIDCLinkRequired is returned when the identity has the email of an
+// existing account. The account owner must confirm the link with the
+// account's password, passing LinkToken to ConfirmLink.
+type ErrOIDCLinkRequired struct {
+	LinkToken string
+}
+
+func (e *ErrOIDCLinkRequired) Error() string {
+	return "an account with this email already exists; confirm with its password to link it"
+}
+
+// OIDCService signs users in with external OpenID Connect providers. An
+// external identity is linked to the user with the same verified email once
+// the user confirms with the account's password, and later recognized by the
+// provider's subject identifier.
+type OIDCService struct {
+	Providers map[string]*OIDCProvider
+	Repo      repositories.OIDCRepositoryInterface
+	Users     repositories.UserRepositoryInterface
+}
+
+func NewOIDCService(providers []*OIDCProvider, repo repositories.OIDCRepositoryInterface,
+	users repositories.UserRepositoryInterface) *OIDCService {
+	byName := make(map[string]*OIDCProvider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Config.Name] = provider
+	}
+	return &OIDCService{Providers: byName, Repo: repo, Users: users}
+}
+
+// BeginLogin starts a login with the provider. It returns the URL to redirect
+// the user to and the state, which the caller must bind to the user agent.
+func (s *OIDCService) BeginLogin(providerName string) (string, string, error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownOIDCProvider
+	}
+
+	state, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	authURL, err := provider.AuthCodeURL(state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.Repo.CreateLoginState(models.OIDCLoginState{
+		StateHash:    utils.HashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(OIDCLoginStateTTL),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// CompleteLogin consumes the state, exchanges the code for an ID token,
+// verifies it and returns the user linked to the external identity.
+func (s *OIDCService) CompleteLogin(providerName, state, code string) (models.User, error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return models.User{}, ErrUnknownOIDCProvider
+	}
+
+	loginState, err := s.Repo.ConsumeLoginState(utils.HashToken(state))
+	if err != nil {
+		if errors.Is(err, repositories.ErrOIDCLoginStateNotFound) {
+			return models.User{}, ErrInvalidOIDCState
+		}
+		return models.User{}, err
+	}
+	if loginState.Provider != providerName || time.Now().After(loginState.ExpiresAt) {
+		return models.User{}, ErrInvalidOIDCState
+	}
+
+	rawIDToken, err := provider.Exchange(code, loginState.CodeVerifier)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	claims, err := provider.VerifyIDToken(rawIDToken, loginState.Nonce)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return s.linkUser(provider, claims)
+}
+
+// linkUser returns the user linked to the identity, or creates an account if
+// the provider allows it. An existing account with the same email is only
+// linked after ConfirmLink.
+func (s *OIDCService) linkUser(provider *OIDCProvider, claims *IDTokenClaims) (models.User, error) {
+	providerName := provider.Config.Name
+
+	user, err := s.identityUser(providerName, claims.Subject)
+	if !errors.Is(err, repositories.ErrUserIdentityNotFound) {
+		return user, err
+	}
+
+	// Linking by email is only safe when the provider vouches for the address
+	if claims.Email == "" || !claims.IsEmailVerified() {
+		return models.User{}, ErrOIDCEmailNotVerified
+	}
+
+	user, err = s.Users.GetUserByEmail(claims.Email)
+	switch {
+	case err == nil:
+		return models.User{}, s.requireLink(providerName, claims, user)
+	case errors.Is(err, repositories.ErrUserNotFound):
+		if !provider.Config.AutoCreateUsers {
+			return models.User{}, ErrOIDCAccountNotFound
+		}
+		if user, err = s.createUser(claims); err != nil {
+			if !errors.Is(err, repositories.ErrEmailInUse) {
+				return models.User{}, err
+			}
+			// A concurrent login took the address first; its account is
+			// only linked like any other existing account
+			if user, err = s.identityUser(providerName, claims.Subject); !errors.Is(err, repositories.ErrUserIdentityNotFound) {
+				return user, err
+			}
+			if user, err = s.Users.GetUserByEmail(claims.Email); err != nil {
+				return models.User{}, err
+			}
+			return models.User{}, s.requireLink(providerName, claims, user)
+		}
+	default:
+		return models.User{}, err
+	}
+
+	err = s.Repo.CreateIdentity(models.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if errors.Is(err, repositories.ErrUserIdentityExists) {
+		// A concurrent login linked the identity first
+		return s.identityUser(providerName, claims.Subject)
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	user.PasswordHash = ""
+	return user, nil
+}
+
+// identityUser returns the user the identity is linked to, or
+// repositories.ErrUserIdentityNotFound.
+func (s *OIDCService) identityUser(providerName, subject string) (models.User, error) {
+	identity, err := s.Repo.GetIdentity(providerName, subject)
+	if err != nil {
+		return models.User{}, err
+	}
+	return s.Users.GetUserByID(identity.UserID)
+}
+
+// requireLink stores a pending link between the identity and the user and
+// returns the ErrOIDCLinkRequired carrying its token. Users whose email isn't
+// verified get ErrOIDCAccountNotVerified instead.
+func (s *OIDCService) requireLink(providerName string, claims *IDTokenClaims, user models.User) error {
+	// Whoever registered the address locally may not own it, and the
+	// provider's word alone must not hand the account over
+	if !user.EmailVerified {
+		return ErrOIDCAccountNotVerified
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.Repo.CreatePendingLink(models.OIDCPendingLink{
+		TokenHash: utils.HashToken(token),
+		UserID:    user.ID,
+		Provider:  providerName,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		ExpiresAt: time.Now().Add(OIDCPendingLinkTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return &ErrOIDCLinkRequired{LinkToken: token}
+}
+
+// TakePendingLink consumes a pending link. Each link can be confirmed once,
+// so a wrong password means starting over at the provider.
+func (s *OIDCService) TakePendingLink(token string) (models.OIDCPendingLink, error) {
+	link, err := s.Repo.ConsumePendingLink(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repositories.ErrOIDCPendingLinkNotFound) {
+			return models.OIDCPendingLink{}, ErrInvalidOIDCLink
+		}
+		return models.OIDCPendingLink{}, err
+	}
+	if time.Now().After(link.ExpiresAt) {
+		return models.OIDCPendingLink{}, ErrInvalidOIDCLink
+	}
+	return link, nil
+}
+
+// ConfirmLink links the identity of a pending link to the local account if
+// password is the account's password, and returns the user.
+func (s *OIDCService) ConfirmLink(link models.OIDCPendingLink, password string) (models.User, error) {
+	user, err := s.Users.GetUserByID(link.UserID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return models.User{}, ErrInvalidOIDCLink
+		}
+		return models.User{}, err
+	}
+	if user.Email != link.Email {
+		return models.User{}, ErrInvalidOIDCLink
+	}
+	if !user.EmailVerified {
+		return models.User{}, ErrOIDCAccountNotVerified
+	}
+	passwordHash, err := s.Users.GetPasswordHash(user.ID)
+	if err != nil {
+		return models.User{}, err
+	}
+	if err := utils.VerifyPassword(passwordHash, password); err != nil {
+		return models.User{}, ErrInvalidOIDCLinkPassword
+	}
+
+	err = s.Repo.CreateIdentity(models.UserIdentity{
+		UserID:   user.ID,
+		Provider: link.Provider,
+		Subject:  link.Subject,
+		Email:    link.Email,
+	})
+	if errors.Is(err, repositories.ErrUserIdentityExists) {
+		// Fine if a concurrent confirmation linked it to the same account
+		identity, err := s.Repo.GetIdentity(link.Provider, link.Subject)
+		if err != nil {
+			return models.User{}, err
+		}
+		if identity.UserID != user.ID {
+			return models.User{}, ErrInvalidOIDCLink
+		}
+		return user, nil
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// createUser creates an account for a first-time federated login. The
+// account gets a random password, which the user can reset to sign in locally.
+func (s *OIDCService) createUser(claims *IDTokenClaims) (models.User, error) {
+	password, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return models.User{}, err
+	}
+
+	user := models.User{
+		Name:          displayName(claims),
+		Email:         claims.Email,
+		PasswordHash:  password,
+		Role:          models.RoleUser,
+		EmailVerified: true,
+	}
+	id, err := s.Users.CreateUser(user)
+	if err != nil {
+		return models.User{}, err
+	}
+	user.ID = id
+	return user, nil
+}
+
+// displayName picks a name that passes the User validation rules.
+func displayName(claims *IDTokenClaims) string {
+	name := strings.TrimSpace(claims.Name)
+	if len([]rune(name)) < 2 {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if runes := []rune(name); len(runes) > 20 {
+		name = string(runes[:20])
+	}
+	if len([]rune(name)) < 2 {
+		name = "user"
+	}
+	return name
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubOIDCProvider is a local OpenID Connect provider serving discovery, its
+// JWKS and a token endpoint that returns the ID token built by IDToken.
+type stubOIDCProvider struct {
+	*httptest.Server
+	Keys    *utils.KeySet
+	IDToken func(code string) IDTokenClaims
+}
+
+func newStubOIDCProvider(t *testing.T) *stubOIDCProvider {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	keys, err := utils.NewKeySet(privateKey, "")
+	assert.NoError(t, err)
+
+	stub := &stubOIDCProvider{Keys: keys}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 stub.URL,
+			"authorization_endpoint": stub.URL + "/authorize",
+			"token_endpoint":         stub.URL + "/token",
+			"jwks_uri":               stub.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(keys.JWKS())
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, ok := r.BasicAuth()
+		if !ok || clientID != "go-crud" || secret != "secret" || r.FormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		idToken, err := keys.Sign(stub.IDToken(r.FormValue("code")))
+		assert.NoError(t, err)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": idToken})
+	})
+	stub.Server = httptest.NewServer(mux)
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+// claims returns valid ID token claims for the subject.
+func (s *stubOIDCProvider) claims(subject, nonce string) IDTokenClaims {
+	now := time.Now()
+	return IDTokenClaims{
+		Nonce:         nonce,
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Name:          "Jane Doe",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.URL,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{"go-crud"},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+		},
+	}
+}
+
+func newTestOIDCService(t *testing.T, stub *stubOIDCProvider, autoCreate bool) (*OIDCService, *repositories.MockOIDCRepositoryInterface, *repositories.MockUserRepositoryInterface) {
+	ctrl := gomock.NewController(t)
+	mockRepo := repositories.NewMockOIDCRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	provider := NewOIDCProvider(OIDCProviderConfig{
+		Name:            "stub",
+		Issuer:          stub.URL,
+		ClientID:        "go-crud",
+		ClientSecret:    "secret",
+		RedirectURL:     "https://app.example.com/login/oidc/stub/callback",
+		AutoCreateUsers: autoCreate,
+	})
+	return NewOIDCService([]*OIDCProvider{provider}, mockRepo, mockUsers), mockRepo, mockUsers
+}
+
+// beginLogin starts a login and returns the state with the stored login state.
+func beginLogin(t *testing.T, service *OIDCService, mockRepo *repositories.MockOIDCRepositoryInterface) (string, models.OIDCLoginState) {
+	var stored models.OIDCLoginState
+	mockRepo.EXPECT().CreateLoginState(gomock.Any()).DoAndReturn(func(state models.OIDCLoginState) error {
+		stored = state
+		return nil
+	})
+
+	authURL, state, err := service.BeginLogin("stub")
+	assert.NoError(t, err)
+
+	parsed, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	assert.Equal(t, state, parsed.Query().Get("state"))
+	assert.Equal(t, stored.Nonce, parsed.Query().Get("nonce"))
+	assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+	assert.Equal(t, utils.HashToken(state), stored.StateHash)
+
+	mockRepo.EXPECT().ConsumeLoginState(stored.StateHash).Return(stored, nil)
+	return state, stored
+}
+
+func TestOIDC_ExistingUserMustConfirmLink(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	service, mockRepo, mockUsers := newTestOIDCService(t, stub, false)
+	state, stored := beginLogin(t, service, mockRepo)
+	stub.IDToken = func(code string) IDTokenClaims { return stub.claims("ext-1", stored.Nonce) }
+
+	var pending models.OIDCPendingLink
+
+	// Mock repository behavior: nothing is linked yet
+	mockRepo.EXPECT().GetIdentity("stub", "ext-1").Return(models.UserIdentity{}, repositories.ErrUserIdentityNotFound)
+	mockUsers.EXPECT().GetUserByEmail("jane@example.com").
+		Return(models.User{ID: 3, Email: "jane@example.com", EmailVerified: true, Role: models.RoleUser}, nil)
+	mockRepo.EXPECT().CreatePendingLink(gomock.Any()).DoAndReturn(func(link models.OIDCPendingLink) error {
+		pending = link
+		return nil
+	})
+
+	// Call the method
+	_, err := service.CompleteLogin("stub", state, "code")
+
+	// Assertions
+	var linkRequired *ErrOIDCLinkRequired
+	assert.ErrorAs(t, err, &linkRequired)
+	assert.Equal(t, utils.HashToken(linkRequired.LinkToken), pending.TokenHash)
+	assert.Equal(t, models.OIDCPendingLink{TokenHash: pending.TokenHash, UserID: 3, Provider: "stub", Subject: "ext-1",
+		Email: "jane@example.com", ExpiresAt: pending.ExpiresAt}, pending)
+}
+
+func TestOIDC_UnverifiedExistingUserIsNotLinked(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	service, mockRepo, mockUsers := newTestOIDCService(t, stub, false)
+	state, stored := beginLogin(t, service, mockRepo)
+	stub.IDToken = func(code string) IDTokenClaims { return stub.claims("ext-1", stored.Nonce) }
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetIdentity("stub", "ext-1").Return(models.UserIdentity{}, repositories.ErrUserIdentityNotFound)
+	mockUsers.EXPECT().GetUserByEmail("jane@example.com").Return(models.User{ID: 3, Email: "jane@example.com"}, nil)
+
+	// Call the method
+	_, err := service.CompleteLogin("stub", state, "code")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrOIDCAccountNotVerified)
+}
+
+func TestOIDC_ConfirmLink(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	service, mockRepo, mockUsers := newTestOIDCService(t, stub, false)
+
+	passwordHash, _ := utils.HashPassword("password")
+	link := models.OIDCPendingLink{UserID: 3, Provider: "stub", Subject: "ext-1", Email: "jane@example.com",
+		ExpiresAt: time.Now().Add(time.Minute)}
+
+	// Mock repository behavior: a wrong password, then the right one
+	mockRepo.EXPECT().ConsumePendingLink(utils.HashToken("link-token")).Return(link, nil)
+	mockUsers.EXPECT().GetUserByID(3).Return(models.User{ID: 3, Email: "jane@example.com", EmailVerified: true}, nil).Times(2)
+	mockUsers.EXPECT().GetPasswordHash(3).Return(passwordHash, nil).Times(2)
+	mockRepo.EXPECT().CreateIdentity(models.UserIdentity{UserID: 3, Provider: "stub", Subject: "ext-1", Email: "jane@example.com"}).Return(nil)
+
+	// Call the methods
+	taken, err := service.TakePendingLink("link-token")
+	assert.NoError(t, err)
+	_, wrongErr := service.ConfirmLink(taken, "wrong-password")
+	user, err := service.ConfirmLink(taken, "password")
+
+	// Assertions
+	assert.ErrorIs(t, wrongErr, ErrInvalidOIDCLinkPassword)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, user.ID)
+}
+
+func TestOIDC_ReturningUserIsFoundBySubject(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	service, mockRepo, mockUsers := newTestOIDCService(t, stub, false)
+	state, stored := beginLogin(t, service, mockRepo)
+
+	// The email changed at the provider, but the subject stays the same
+	stub.IDToken = func(code string) IDTokenClaims {
+		claims := stub.claims("ext-1", stored.Nonce)
+		claims.Email, claims.EmailVerified = "jane@new.example.com", "false"
+		return claims
+	}
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetIdentity("stub", "ext-1").Return(models.UserIdentity{UserID: 3}, nil)
+	mockUsers.EXPECT().GetUserByID(3).Return(models.User{ID: 3}, nil)
+
+	// Call the method
+	user, err := service.CompleteLogin("stub", state, "code")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 3, user.ID)
+}
+
+func TestOIDC_AutoCreatesUser(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	service, mockRepo, mockUsers := newTestOIDCService(t, stub, true)
+	state, stored := beginLogin(t, service, mockRepo)
+	stub.IDToken = func(code string) IDTokenClaims { return stub.claims("ext-1", stored.Nonce) }
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetIdentity("stub", "ext-1").Return(models.UserIdentity{}, repositories.ErrUserIdentityNotFound)
+	mockUsers.EXPECT().GetUserByEmail("jane@example.com").Return(models.User{}, repositories.ErrUserNotFound)
+	mockUsers.EXPECT().CreateUser(gomock.Any()).DoAndReturn(func(user models.User) (int, error) {
+		assert.Equal(t, "Jane Doe", user.Name)
+		assert.Equal(t, models.RoleUser, user.Role)
+		assert.True(t, user.EmailVerified)
+		assert.NoError(t, models.Validate.Struct(user))
+		return 4, nil
+	})
+	mockRepo.EXPECT().CreateIdentity(gomock.Any()).Return(nil)
+
+	// Call the method
+	user, err := service.CompleteLogin("stub", state, "code")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 4, user.ID)
+	assert.Empty(t, user.PasswordHash)
+}
+
+func TestOIDC_AutoCreateLosesEmailRace(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	service, mockRepo, mockUsers := newTestOIDCService(t, stub, true)
+	state, stored := beginLogin(t, service, mockRepo)
+	stub.IDToken = func(code string) IDTokenClaims { return stub.claims("ext-1", stored.Nonce) }
+
+	// Mock repository behavior: another login creates the account first
+	mockRepo.EXPECT().GetIdentity("stub", "ext-1").Return(models.UserIdentity{}, repositories.ErrUserIdentityNotFound).Times(2)
+	gomock.InOrder(
+		mockUsers.EXPECT().GetUserByEmail("jane@example.com").Return(models.User{}, repositories.ErrUserNotFound),
+		mockUsers.EXPECT().CreateUser(gomock.Any()).Return(0, repositories.ErrEmailInUse),
+		mockUsers.EXPECT().GetUserByEmail("jane@example.com").Return(models.User{ID: 5, Email: "jane@example.com", EmailVerified: true}, nil),
+	)
+	mockRepo.EXPECT().CreatePendingLink(gomock.Any()).Return(nil)
+
+	// Call the method
+	_, err := service.CompleteLogin("stub", state, "code")
+
+	// Assertions
+	var linkRequired *ErrOIDCLinkRequired
+	assert.ErrorAs(t, err, &linkRequired)
+}
+
+func TestOIDC_AutoCreateLosesIdentityRace(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	service, mockRepo, mockUsers := newTestOIDCService(t, stub, true)
+	state, stored := beginLogin(t, service, mockRepo)
+	stub.IDToken = func(code string) IDTokenClaims { return stub.claims("ext-1", stored.Nonce) }
+
+	// Mock repository behavior: another login links the identity first
+	gomock.InOrder(
+		mockRepo.EXPECT().GetIdentity("stub", "ext-1").Return(models.UserIdentity{}, repositories.ErrUserIdentityNotFound),
+		mockRepo.EXPECT().CreateIdentity(gomock.Any()).Return(repositories.ErrUserIdentityExists),
+		mockRepo.EXPECT().GetIdentity("stub", "ext-1").Return(models.UserIdentity{UserID: 3}, nil),
+	)
+	mockUsers.EXPECT().GetUserByEmail("jane@example.com").Return(models.User{}, repositories.ErrUserNotFound)
+	mockUsers.EXPECT().CreateUser(gomock.Any()).Return(4, nil)
+	mockUsers.EXPECT().GetUserByID(3).Return(models.User{ID: 3}, nil)
+
+	// Call the method
+	user, err := service.CompleteLogin("stub", state, "code")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 3, user.ID)
+}
+
+func TestOIDC_UnknownEmailWithoutAutoCreate(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	service, mockRepo, mockUsers := newTestOIDCService(t, stub, false)
+	state, stored := beginLogin(t, service, mockRepo)
+	stub.IDToken = func(code string) IDTokenClaims { return stub.claims("ext-1", stored.Nonce) }
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetIdentity("stub", "ext-1").Return(models.UserIdentity{}, repositories.ErrUserIdentityNotFound)
+	mockUsers.EXPECT().GetUserByEmail("jane@example.com").Return(models.User{}, repositories.ErrUserNotFound)
+
+	// Call the method
+	_, err := service.CompleteLogin("stub", state, "code")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrOIDCAccountNotFound)
+}
+
+func TestOIDC_RejectsInvalidIDTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(claims *IDTokenClaims)
+		want   error
+	}{
+		{"wrong nonce", func(c *IDTokenClaims) { c.Nonce = "other" }, ErrInvalidIDToken},
+		{"wrong audience", func(c *IDTokenClaims) { c.Audience = jwt.ClaimStrings{"other-app"} }, ErrInvalidIDToken},
+		{"wrong issuer", func(c *IDTokenClaims) { c.Issuer = "https://evil.example.com" }, ErrInvalidIDToken},
+		{"expired", func(c *IDTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) }, ErrInvalidIDToken},
+		{"other azp", func(c *IDTokenClaims) {
+			c.Audience = jwt.ClaimStrings{"go-crud", "other-app"}
+			c.AuthorizedParty = "other-app"
+		}, ErrInvalidIDToken},
+		{"email not verified", func(c *IDTokenClaims) { c.EmailVerified = false }, ErrOIDCEmailNotVerified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := newStubOIDCProvider(t)
+			service, mockRepo, _ := newTestOIDCService(t, stub, true)
+			state, stored := beginLogin(t, service, mockRepo)
+			stub.IDToken = func(code string) IDTokenClaims {
+				claims := stub.claims("ext-1", stored.Nonce)
+				tt.modify(&claims)
+				return claims
+			}
+			if tt.want == ErrOIDCEmailNotVerified {
+				mockRepo.EXPECT().GetIdentity("stub", "ext-1").Return(models.UserIdentity{}, repositories.ErrUserIdentityNotFound)
+			}
+
+			_, err := service.CompleteLogin("stub", state, "code")
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestOIDC_RejectsTokenSignedByAnotherKey(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	service, _, _ := newTestOIDCService(t, stub, true)
+
+	// A forged token reusing the kid of the provider's key
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	forger, err := utils.NewKeySet(otherKey, stub.Keys.JWKS().Keys[0].Kid)
+	assert.NoError(t, err)
+	idToken, err := forger.Sign(stub.claims("ext-1", "nonce"))
+	assert.NoError(t, err)
+
+	// Call the method
+	_, err = service.Providers["stub"].VerifyIDToken(idToken, "nonce")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+func TestOIDC_StateIsSingleUse(t *testing.T) {
+	stub := newStubOIDCProvider(t)
+	service, mockRepo, _ := newTestOIDCService(t, stub, true)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().ConsumeLoginState(utils.HashToken("used-state")).Return(models.OIDCLoginState{}, repositories.ErrOIDCLoginStateNotFound)
+
+	// Call the method
+	_, err := service.CompleteLogin("stub", "used-state", "code")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrInvalidOIDCState)
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy configures the rules new passwords must satisfy.
+type PasswordPolicy struct {
+	MinLength            int  // In characters
+	MaxLength            int  // In characters; bcrypt only uses the first 72 bytes
+	MinCharacterClasses  int  // Of lowercase, uppercase, digits and symbols
+	DisallowPersonalInfo bool // Reject passwords containing the user's name or email
+	HistorySize          int  // Number of previous passwords that can't be reused
+}
+
+// DefaultPasswordPolicy follows NIST SP 800-63B: length over composition
+// rules, and no reuse of recent or breached passwords.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:            8,
+		MaxLength:            128,
+		DisallowPersonalInfo: true,
+		HistorySize:          5,
+	}
+}
+
+// PasswordPolicyError lists every rule a password violates.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Violations, "; ")
+}
+
+// BreachedPasswordChecker reports whether a password is known from a data breach.
+type BreachedPasswordChecker interface {
+	Contains(password string) bool
+}
+
+// PasswordPolicyService enforces the password policy and keeps the history of
+// previous passwords.
+type PasswordPolicyService struct {
+	Policy   PasswordPolicy
+	History  repositories.PasswordHistoryRepositoryInterface
+	Breached BreachedPasswordChecker // Optional
+}
+
+func NewPasswordPolicyService(policy PasswordPolicy, history repositories.PasswordHistoryRepositoryInterface,
+	breached BreachedPasswordChecker) *PasswordPolicyService {
+	return &PasswordPolicyService{Policy: policy, History: history, Breached: breached}
+}
+
+// Check validates a new password for the user. currentHash is the user's
+// current password hash, or empty for a new account.
+func (s *PasswordPolicyService) Check(password string, user models.User, currentHash string) error {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < s.Policy.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", s.Policy.MinLength))
+	}
+	if s.Policy.MaxLength > 0 && length > s.Policy.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d characters", s.Policy.MaxLength))
+	}
+	if classes := characterClasses(password); classes < s.Policy.MinCharacterClasses {
+		violations = append(violations, fmt.Sprintf("must contain at least %d of lowercase letters, uppercase letters, digits and symbols",
+			s.Policy.MinCharacterClasses))
+	}
+	if s.Policy.DisallowPersonalInfo && containsPersonalInfo(password, user) {
+		violations = append(violations, "must not contain your name or email address")
+	}
+	if s.Breached != nil && s.Breached.Contains(password) {
+		violations = append(violations, "has appeared in a data breach")
+	}
+
+	if currentHash != "" && s.Policy.HistorySize > 0 {
+		reused, err := s.reused(user.ID, password, currentHash)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violations = append(violations, fmt.Sprintf("must not match any of your last %d passwords", s.Policy.HistorySize))
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Remember records the hash being replaced, so that it can't be reused.
+func (s *PasswordPolicyService) Remember(userID int, previousHash string) error {
+	if s.Policy.HistorySize <= 1 || previousHash == "" {
+		return nil
+	}
+	// The current password counts towards the history size
+	return s.History.AddPasswordHistory(userID, previousHash, s.Policy.HistorySize-1)
+}
+
+// reused reports whether the password matches the current one or one of the
+// previous ones within the history size.
+func (s *PasswordPolicyService) reused(userID int, password, currentHash string) (bool, error) {
+	hashes := []string{currentHash}
+	if s.Policy.HistorySize > 1 {
+		previous, err := s.History.GetPasswordHistory(userID, s.Policy.HistorySize-1)
+		if err != nil {
+			return false, err
+		}
+		hashes = append(hashes, previous...)
+	}
+
+	for _, hash := range hashes {
+		err := utils.VerifyPassword(hash, password)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, utils.ErrPasswordMismatch) && !errors.Is(err, utils.ErrUnknownPasswordHash) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// containsPersonalInfo reports whether the password contains the user's
+// email address, its local part, or a part of the name of 3 or more characters.
+func containsPersonalInfo(password string, user models.User) bool {
+	password = strings.ToLower(password)
+
+	var parts []string
+	if user.Email != "" {
+		email := strings.ToLower(user.Email)
+		local, _, _ := strings.Cut(email, "@")
+		parts = append(parts, email, local)
+	}
+	parts = append(parts, strings.FieldsFunc(strings.ToLower(user.Name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})...)
+
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= 3 && strings.Contains(password, part) {
+			return true
+		}
+	}
+	return false
+}
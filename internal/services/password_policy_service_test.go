@@ -0,0 +1,109 @@
+package services
+
+import (
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// breachedList is a BreachedPasswordChecker backed by a fixed list.
+type breachedList []string
+
+func (l breachedList) Contains(password string) bool {
+	for _, p := range l {
+		if p == password {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPasswordPolicy_Rules(t *testing.T) {
+	policy := DefaultPasswordPolicy()
+	policy.MinCharacterClasses = 3
+	policy.MaxLength = 20
+	service := NewPasswordPolicyService(policy, nil, breachedList{"Password123!"})
+	user := models.User{Name: "John Smith", Email: "jsmith@gmail.com"}
+
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"valid", "Tr0ub4dor&3x", true},
+		{"too short", "Ab1!", false},
+		{"too long", "Tr0ub4dor&3xTr0ub4dor&3x", false},
+		{"too few character classes", "troubadourhorse", false},
+		{"contains name", "Smith-2024-rocks", false},
+		{"contains email local part", "xJSmith!99", false},
+		{"breached", "Password123!", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.Check(tt.password, user, "")
+			if tt.valid {
+				assert.NoError(t, err)
+				return
+			}
+			var policyErr *PasswordPolicyError
+			assert.ErrorAs(t, err, &policyErr)
+			assert.Len(t, policyErr.Violations, 1)
+		})
+	}
+}
+
+func TestPasswordPolicy_RejectsRecentPasswords(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockHistory := repositories.NewMockPasswordHistoryRepositoryInterface(ctrl)
+	service := NewPasswordPolicyService(DefaultPasswordPolicy(), mockHistory, nil)
+	user := models.User{ID: 1, Name: "John", Email: "john@gmail.com"}
+
+	currentHash, _ := utils.HashPassword("current-password")
+	previousHash, _ := utils.HashPassword("previous-password")
+
+	// Mock repository behavior
+	mockHistory.EXPECT().GetPasswordHistory(1, 4).Return([]string{previousHash}, nil).Times(3)
+
+	// Assertions
+	assert.Error(t, service.Check("current-password", user, currentHash))
+	assert.Error(t, service.Check("previous-password", user, currentHash))
+	assert.NoError(t, service.Check("brand-new-password", user, currentHash))
+}
+
+func TestUpdateUser_EnforcesPolicyAndRecordsHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	mockHistory := repositories.NewMockPasswordHistoryRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, NewPasswordPolicyService(DefaultPasswordPolicy(), mockHistory, nil))
+
+	currentHash, _ := utils.HashPassword("current-password")
+	weak := "short"
+	strong := "brand-new-password"
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Name: "John", Email: "john@gmail.com"}, nil).Times(2)
+	mockRepo.EXPECT().GetPasswordHash(1).Return(currentHash, nil).Times(2)
+	mockHistory.EXPECT().GetPasswordHistory(1, 4).Return(nil, nil).Times(2)
+	mockRepo.EXPECT().UpdateUser(1, gomock.Any()).Return(nil)
+	mockHistory.EXPECT().AddPasswordHistory(1, currentHash, 4).Return(nil)
+
+	// Call the method with a password violating the policy
+	_, err := service.UpdateUser(1, models.UpdateUserRequest{PasswordHash: &weak}, nil)
+	var policyErr *PasswordPolicyError
+	assert.ErrorAs(t, err, &policyErr)
+
+	// Call the method with a valid password
+	_, err = service.UpdateUser(1, models.UpdateUserRequest{PasswordHash: &strong}, nil)
+	assert.NoError(t, err)
+}
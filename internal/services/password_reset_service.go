@@ -0,0 +1,130 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"time"
+)
+
+// PasswordResetTTL is how long a password reset token stays valid.
+const PasswordResetTTL = time.Hour
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// PasswordResetService issues password reset tokens and redeems them.
+type PasswordResetService struct {
+	Tokens      repositories.ActionTokenRepositoryInterface
+	Users       repositories.UserRepositoryInterface
+	Mailer      Mailer
+	AuthService *AuthService
+	Passwords   *PasswordPolicyService // Optional; enforces the password policy when set
+	ResetURL    string                 // Link sent to users; the token is appended as a query parameter
+}
+
+func NewPasswordResetService(tokens repositories.ActionTokenRepositoryInterface, users repositories.UserRepositoryInterface,
+	mailer Mailer, authService *AuthService, passwords *PasswordPolicyService, resetURL string) *PasswordResetService {
+	return &PasswordResetService{
+		Tokens:      tokens,
+		Users:       users,
+		Mailer:      mailer,
+		AuthService: authService,
+		Passwords:   passwords,
+		ResetURL:    resetURL,
+	}
+}
+
+// RequestReset emails a reset token to the user with the given email. Unknown
+// emails are ignored so callers can't tell which accounts exist.
+func (s *PasswordResetService) RequestReset(email string) error {
+	user, err := s.Users.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Tokens.CreateActionToken(models.ActionToken{
+		UserID:    user.ID,
+		Purpose:   models.PurposePasswordReset,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(PasswordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Use the following token to reset your password: %s", token)
+	if s.ResetURL != "" {
+		link, err := linkWithToken(s.ResetURL, token)
+		if err != nil {
+			return err
+		}
+		body = fmt.Sprintf("Reset your password using this link: %s", link)
+	}
+	return s.Mailer.Send(user.Email, "Reset your password", body)
+}
+
+// ResetPassword redeems a reset token, sets the new password and revokes every
+// existing session of the user, along with the user's other reset tokens.
+func (s *PasswordResetService) ResetPassword(token, newPassword string) error {
+	stored, err := s.Tokens.GetActionTokenByHash(models.PurposePasswordReset, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repositories.ErrActionTokenNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	if stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	// Checked before consuming the token, so the user can pick another password
+	var currentHash string
+	if s.Passwords != nil {
+		user, err := s.Users.GetUserByID(stored.UserID)
+		if err != nil {
+			return err
+		}
+		if currentHash, err = s.Users.GetPasswordHash(stored.UserID); err != nil {
+			return err
+		}
+		if err := s.Passwords.Check(newPassword, user, currentHash); err != nil {
+			return err
+		}
+	}
+
+	marked, err := s.Tokens.MarkActionTokenUsed(stored.ID)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.Users.UpdatePassword(stored.UserID, hashedPassword); err != nil {
+		return err
+	}
+	// Other reset links sent before must not be able to change it again
+	if err := s.Tokens.InvalidateActionTokens(stored.UserID, models.PurposePasswordReset); err != nil {
+		return err
+	}
+	if s.Passwords != nil {
+		if err := s.Passwords.Remember(stored.UserID, currentHash); err != nil {
+			return err
+		}
+	}
+	return s.AuthService.RevokeAllForUser(stored.UserID)
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMailer records sent messages instead of delivering them.
+type fakeMailer struct {
+	to, subject, body string
+	sent              int
+}
+
+func (m *fakeMailer) Send(to, subject, body string) error {
+	m.to, m.subject, m.body = to, subject, body
+	m.sent++
+	return nil
+}
+
+func TestRequestReset_SendsToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	mailer := &fakeMailer{}
+	service := NewPasswordResetService(mockTokens, mockUsers, mailer, nil, nil, "https://example.com/reset?lang=en")
+
+	var storedHash string
+
+	// Mock repository behavior
+	mockUsers.EXPECT().GetUserByEmail("john@gmail.com").Return(models.User{ID: 1, Email: "john@gmail.com"}, nil)
+	mockTokens.EXPECT().CreateActionToken(gomock.Any()).DoAndReturn(func(token models.ActionToken) (int, error) {
+		assert.Equal(t, models.PurposePasswordReset, token.Purpose)
+		storedHash = token.TokenHash
+		return 1, nil
+	})
+
+	// Call the method
+	err := service.RequestReset("john@gmail.com")
+
+	// Assertions: only the hash of the emailed token is stored
+	assert.NoError(t, err)
+	assert.Equal(t, 1, mailer.sent)
+	assert.Equal(t, "john@gmail.com", mailer.to)
+	link, err := url.Parse(mailer.body[strings.Index(mailer.body, "https://"):])
+	assert.NoError(t, err)
+	assert.Equal(t, "en", link.Query().Get("lang"))
+	assert.Equal(t, utils.HashToken(link.Query().Get("token")), storedHash)
+}
+
+func TestRequestReset_UnknownEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	mailer := &fakeMailer{}
+	service := NewPasswordResetService(mockTokens, mockUsers, mailer, nil, nil, "")
+
+	// Mock repository behavior
+	mockUsers.EXPECT().GetUserByEmail("nobody@gmail.com").Return(models.User{}, repositories.ErrUserNotFound)
+
+	// Call the method
+	err := service.RequestReset("nobody@gmail.com")
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 0, mailer.sent)
+}
+
+func TestResetPassword_RevokesSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	mockRefreshTokens := repositories.NewMockRefreshTokenRepositoryInterface(ctrl)
+	mockSessions := repositories.NewMockSessionRepositoryInterface(ctrl)
+	mockRevocations := repositories.NewMockRevocationRepositoryInterface(ctrl)
+	authService := NewAuthService(mockRefreshTokens, mockSessions, mockUsers, NewRevocationService(mockRevocations), newTestTokenService())
+	service := NewPasswordResetService(mockTokens, mockUsers, &fakeMailer{}, authService, nil, "")
+
+	stored := models.ActionToken{ID: 3, UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Mock repository behavior
+	mockTokens.EXPECT().GetActionTokenByHash(models.PurposePasswordReset, utils.HashToken("reset-token")).Return(stored, nil)
+	mockTokens.EXPECT().MarkActionTokenUsed(3).Return(true, nil)
+	mockUsers.EXPECT().UpdatePassword(1, gomock.Any()).Return(nil)
+	mockTokens.EXPECT().InvalidateActionTokens(1, models.PurposePasswordReset).Return(nil)
+	mockRefreshTokens.EXPECT().RevokeUserRefreshTokens(1).Return(nil)
+	mockSessions.EXPECT().RevokeUserSessions(1).Return(nil)
+	mockRevocations.EXPECT().RevokeUserTokens(1, gomock.Any()).Return(nil)
+
+	// Call the method
+	err := service.ResetPassword("reset-token", "new-password")
+
+	// Assertions
+	assert.NoError(t, err)
+}
+
+func TestResetPassword_RevokesOAuthRefreshTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	mockRefreshTokens := repositories.NewMockRefreshTokenRepositoryInterface(ctrl)
+	mockSessions := repositories.NewMockSessionRepositoryInterface(ctrl)
+	mockRevocations := repositories.NewMockRevocationRepositoryInterface(ctrl)
+	mockOAuth := repositories.NewMockOAuthRepositoryInterface(ctrl)
+	authService := NewAuthService(mockRefreshTokens, mockSessions, mockUsers, NewRevocationService(mockRevocations), newTestTokenService())
+	authService.OAuth = mockOAuth
+	service := NewPasswordResetService(mockTokens, mockUsers, &fakeMailer{}, authService, nil, "")
+	oauth := NewOAuthService(mockOAuth, mockUsers, newTestTokenService(), nil)
+
+	oauthToken := models.OAuthRefreshToken{ID: 5, ClientID: "web-app", UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Mock repository behavior
+	mockTokens.EXPECT().GetActionTokenByHash(models.PurposePasswordReset, gomock.Any()).
+		Return(models.ActionToken{ID: 3, UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	mockTokens.EXPECT().MarkActionTokenUsed(3).Return(true, nil)
+	mockUsers.EXPECT().UpdatePassword(1, gomock.Any()).Return(nil)
+	mockTokens.EXPECT().InvalidateActionTokens(1, models.PurposePasswordReset).Return(nil)
+	mockRefreshTokens.EXPECT().RevokeUserRefreshTokens(1).Return(nil)
+	mockOAuth.EXPECT().RevokeUserOAuthRefreshTokens(1).DoAndReturn(func(userID int) error {
+		revokedAt := time.Now()
+		oauthToken.RevokedAt = &revokedAt
+		return nil
+	})
+	mockSessions.EXPECT().RevokeUserSessions(1).Return(nil)
+	mockRevocations.EXPECT().RevokeUserTokens(1, gomock.Any()).Return(nil)
+	mockOAuth.EXPECT().GetOAuthRefreshToken(utils.HashToken("oauth-refresh")).DoAndReturn(func(string) (models.OAuthRefreshToken, error) {
+		return oauthToken, nil
+	})
+	mockOAuth.EXPECT().RevokeOAuthRefreshTokens("web-app", 1).Return(nil)
+
+	// Call the method
+	err := service.ResetPassword("reset-token", "new-password")
+	assert.NoError(t, err)
+	_, refreshErr := oauth.RefreshToken(testOAuthClient(), "oauth-refresh", "")
+
+	// Assertions: the OAuth client can't refresh after the reset
+	assert.ErrorContains(t, refreshErr, "invalid_grant")
+}
+
+func TestResetPassword_UsedToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mockUsers := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewPasswordResetService(mockTokens, mockUsers, &fakeMailer{}, nil, nil, "")
+
+	usedAt := time.Now().Add(-time.Minute)
+	stored := models.ActionToken{ID: 3, UserID: 1, ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt}
+
+	// Mock repository behavior
+	mockTokens.EXPECT().GetActionTokenByHash(models.PurposePasswordReset, gomock.Any()).Return(stored, nil)
+
+	// Call the method
+	err := service.ResetPassword("reset-token", "new-password")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"go-crud/internal/repositories"
+	"sync"
+	"time"
+)
+
+// DefaultRevocationCacheTTL bounds how long another instance may keep
+// accepting a token after it was revoked elsewhere.
+const DefaultRevocationCacheTTL = 30 * time.Second
+
+// maxRevocationCacheEntries triggers a sweep of expired cache entries.
+const maxRevocationCacheEntries = 10000
+
+type revocationCacheEntry struct {
+	revoked   bool
+	before    time.Time
+	expiresAt time.Time
+}
+
+// RevocationService records revoked access tokens in Postgres and keeps an
+// in-process cache so the auth middleware doesn't hit the database on every request.
+type RevocationService struct {
+	Repo     repositories.RevocationRepositoryInterface
+	CacheTTL time.Duration
+
+	mu       sync.Mutex
+	tokens   map[string]revocationCacheEntry
+	users    map[int]revocationCacheEntry
+	sessions map[int]revocationCacheEntry
+}
+
+func NewRevocationService(repo repositories.RevocationRepositoryInterface) *RevocationService {
+	return &RevocationService{
+		Repo:     repo,
+		CacheTTL: DefaultRevocationCacheTTL,
+		tokens:   make(map[string]revocationCacheEntry),
+		users:    make(map[int]revocationCacheEntry),
+		sessions: make(map[int]revocationCacheEntry),
+	}
+}
+
+// RevokeToken revokes a single access token until it expires.
+func (s *RevocationService) RevokeToken(jti string, expiresAt time.Time) error {
+	if err := s.Repo.RevokeToken(jti, expiresAt); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[jti] = revocationCacheEntry{revoked: true, expiresAt: expiresAt}
+	return nil
+}
+
+// ConsumeToken revokes a single-use token and reports whether it was still
+// unused. Concurrent callers can't both consume the same token.
+func (s *RevocationService) ConsumeToken(jti string, expiresAt time.Time) (bool, error) {
+	consumed, err := s.Repo.ConsumeToken(jti, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[jti] = revocationCacheEntry{revoked: true, expiresAt: expiresAt}
+	return consumed, nil
+}
+
+// RevokeUserTokens revokes every access token issued to the user so far.
+func (s *RevocationService) RevokeUserTokens(userID int) error {
+	// JWT iat has second precision, so every token issued in this second is
+	// revoked too, including any issued right after this call.
+	before := time.Now().Truncate(time.Second)
+	if err := s.Repo.RevokeUserTokens(userID, before); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userID] = revocationCacheEntry{before: before, expiresAt: time.Now().Add(s.CacheTTL)}
+	return nil
+}
+
+// RevokeSession revokes every access token issued for the login session.
+func (s *RevocationService) RevokeSession(sessionID int) error {
+	if err := s.Repo.RevokeSession(sessionID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[sessionID] = revocationCacheEntry{revoked: true, expiresAt: time.Now().Add(s.CacheTTL)}
+	return nil
+}
+
+// IsRevoked reports whether the token identified by jti, issued to userID at
+// issuedAt, has been revoked individually, as part of a revoke-all, or along
+// with its login session. sessionID is 0 for tokens not bound to a session.
+func (s *RevocationService) IsRevoked(jti string, userID, sessionID int, issuedAt time.Time) (bool, error) {
+	before, err := s.userRevokedBefore(userID)
+	if err != nil {
+		return false, err
+	}
+	if !before.IsZero() && !issuedAt.After(before) {
+		return true, nil
+	}
+	if sessionID != 0 {
+		revoked, err := s.sessionRevoked(sessionID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+	return s.tokenRevoked(jti)
+}
+
+func (s *RevocationService) tokenRevoked(jti string) (bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.tokens[jti]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := s.Repo.IsTokenRevoked(jti)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	s.tokens[jti] = revocationCacheEntry{revoked: revoked, expiresAt: now.Add(s.CacheTTL)}
+	return revoked, nil
+}
+
+func (s *RevocationService) sessionRevoked(sessionID int) (bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.sessions[sessionID]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := s.Repo.IsSessionRevoked(sessionID)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	s.sessions[sessionID] = revocationCacheEntry{revoked: revoked, expiresAt: now.Add(s.CacheTTL)}
+	return revoked, nil
+}
+
+func (s *RevocationService) userRevokedBefore(userID int) (time.Time, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.users[userID]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.before, nil
+	}
+
+	before, err := s.Repo.GetUserTokensRevokedBefore(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+	s.users[userID] = revocationCacheEntry{before: before, expiresAt: now.Add(s.CacheTTL)}
+	return before, nil
+}
+
+// sweep drops expired entries once the cache grows large. Callers must hold s.mu.
+func (s *RevocationService) sweep(now time.Time) {
+	if len(s.tokens)+len(s.users)+len(s.sessions) < maxRevocationCacheEntries {
+		return
+	}
+	for jti, entry := range s.tokens {
+		if now.After(entry.expiresAt) {
+			delete(s.tokens, jti)
+		}
+	}
+	for userID, entry := range s.users {
+		if now.After(entry.expiresAt) {
+			delete(s.users, userID)
+		}
+	}
+	for sessionID, entry := range s.sessions {
+		if now.After(entry.expiresAt) {
+			delete(s.sessions, sessionID)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"go-crud/internal/repositories"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRevoked_CachesLookups(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockRevocationRepositoryInterface(ctrl)
+	service := NewRevocationService(mockRepo)
+
+	// The repository must only be hit once per key within the cache TTL
+	mockRepo.EXPECT().GetUserTokensRevokedBefore(1).Return(time.Time{}, nil).Times(1)
+	mockRepo.EXPECT().IsTokenRevoked("jti").Return(false, nil).Times(1)
+
+	for i := 0; i < 3; i++ {
+		revoked, err := service.IsRevoked("jti", 1, 0, time.Now())
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	}
+}
+
+func TestIsRevoked_AfterRevokeToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockRevocationRepositoryInterface(ctrl)
+	service := NewRevocationService(mockRepo)
+
+	expiresAt := time.Now().Add(time.Minute)
+	mockRepo.EXPECT().RevokeToken("jti", expiresAt).Return(nil)
+	mockRepo.EXPECT().GetUserTokensRevokedBefore(1).Return(time.Time{}, nil)
+
+	assert.NoError(t, service.RevokeToken("jti", expiresAt))
+
+	// The revocation is served from the cache without another lookup
+	revoked, err := service.IsRevoked("jti", 1, 0, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestIsRevoked_RevokeAllForUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockRevocationRepositoryInterface(ctrl)
+	service := NewRevocationService(mockRepo)
+
+	mockRepo.EXPECT().RevokeUserTokens(1, gomock.Any()).Return(nil)
+
+	issuedAt := time.Now().Truncate(time.Second)
+	assert.NoError(t, service.RevokeUserTokens(1))
+
+	// Tokens issued before the revocation are rejected
+	revoked, err := service.IsRevoked("jti", 1, 0, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// So are tokens issued earlier in the same second, whose iat is truncated
+	revoked, err = service.IsRevoked("jti", 1, 0, issuedAt)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestIsRevoked_AfterRevokeSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockRevocationRepositoryInterface(ctrl)
+	service := NewRevocationService(mockRepo)
+
+	mockRepo.EXPECT().RevokeSession(5).Return(nil)
+	mockRepo.EXPECT().GetUserTokensRevokedBefore(1).Return(time.Time{}, nil)
+	mockRepo.EXPECT().IsSessionRevoked(6).Return(false, nil)
+	mockRepo.EXPECT().IsTokenRevoked("other-jti").Return(false, nil)
+
+	assert.NoError(t, service.RevokeSession(5))
+
+	// Tokens of the revoked session are rejected, those of other sessions are not
+	revoked, err := service.IsRevoked("jti", 1, 5, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = service.IsRevoked("other-jti", 1, 6, time.Now())
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestConsumeToken_OnlyOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockRevocationRepositoryInterface(ctrl)
+	service := NewRevocationService(mockRepo)
+
+	// Mock repository behavior: the first insert wins, the second conflicts
+	expiresAt := time.Now().Add(time.Minute)
+	gomock.InOrder(
+		mockRepo.EXPECT().ConsumeToken("jti", expiresAt).Return(true, nil),
+		mockRepo.EXPECT().ConsumeToken("jti", expiresAt).Return(false, nil),
+	)
+	mockRepo.EXPECT().GetUserTokensRevokedBefore(1).Return(time.Time{}, nil)
+
+	// Call the method
+	first, err := service.ConsumeToken("jti", expiresAt)
+	assert.NoError(t, err)
+	second, err := service.ConsumeToken("jti", expiresAt)
+	assert.NoError(t, err)
+
+	// Assertions
+	assert.True(t, first)
+	assert.False(t, second)
+	revoked, err := service.IsRevoked("jti", 1, 0, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"go-crud/internal/models"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Formats of user exports.
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatNDJSON  = "ndjson"
+	ExportFormatParquet = "parquet"
+)
+
+// parquetRowGroupSize is the number of users per Parquet row group, which the
+// writer buffers in memory.
+const parquetRowGroupSize = 10000
+
+var ErrUnknownExportFormat = errors.New("export format must be csv, ndjson or parquet")
+
+// UserExportWriter writes exported users in one format. Close writes
+// whatever the format needs after the last user.
+type UserExportWriter interface {
+	Write(record models.UserExportRecord) error
+	Close() error
+}
+
+// NewUserExportWriter returns a writer of the format to w.
+func NewUserExportWriter(format string, w io.Writer) (UserExportWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		return newCSVUserExportWriter(w)
+	case ExportFormatNDJSON:
+		return &ndjsonUserExportWriter{encoder: json.NewEncoder(w)}, nil
+	case ExportFormatParquet:
+		return &parquetUserExportWriter{
+			writer: parquet.NewGenericWriter[models.UserExportRecord](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize)),
+		}, nil
+	}
+	return nil, ErrUnknownExportFormat
+}
+
+// csvUserExportHeader names the columns of CSV exports.
+var csvUserExportHeader = []string{"id", "name", "email", "role", "email_verified", "version", "created_at", "deleted_at"}
+
+type csvUserExportWriter struct {
+	writer *csv.Writer
+}
+
+func newCSVUserExportWriter(w io.Writer) (*csvUserExportWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvUserExportHeader); err != nil {
+		return nil, err
+	}
+	return &csvUserExportWriter{writer: writer}, nil
+}
+
+func (e *csvUserExportWriter) Write(record models.UserExportRecord) error {
+	deletedAt := ""
+	if record.DeletedAt != nil {
+		deletedAt = record.DeletedAt.Format(time.RFC3339Nano)
+	}
+	return e.writer.Write([]string{
+		strconv.Itoa(record.ID),
+		csvSafe(record.Name),
+		csvSafe(record.Email),
+		record.Role,
+		strconv.FormatBool(record.EmailVerified),
+		strconv.Itoa(record.Version),
+		record.CreatedAt.Format(time.RFC3339Nano),
+		deletedAt,
+	})
+}
+
+// csvSafe prefixes a cell that spreadsheet applications would evaluate as a
+// formula with a quote, so that exported names can't inject formulas.
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func (e *csvUserExportWriter) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type ndjsonUserExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonUserExportWriter) Write(record models.UserExportRecord) error {
+	return e.encoder.Encode(record)
+}
+
+func (e *ndjsonUserExportWriter) Close() error {
+	return nil
+}
+
+type parquetUserExportWriter struct {
+	writer *parquet.GenericWriter[models.UserExportRecord]
+}
+
+func (e *parquetUserExportWriter) Write(record models.UserExportRecord) error {
+	_, err := e.writer.Write([]models.UserExportRecord{record})
+	return err
+}
+
+func (e *parquetUserExportWriter) Close() error {
+	return e.writer.Close()
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"bytes"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+)
+
+var exportedAt = time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+// exportTestUsers mocks the export of two users, one of them deleted, with
+// their password hashes set.
+func exportTestUsers(mockRepo *repositories.MockUserRepositoryInterface, filter models.UserFilter) {
+	users := []models.User{
+		{ID: 1, Name: "John", Email: "john@gmail.com", PasswordHash: "$argon2id$secret", Role: models.RoleAdmin, Version: 2, CreatedAt: exportedAt},
+		{ID: 2, Name: "Doe, Jane", Email: "jane@gmail.com", PasswordHash: "$argon2id$secret", Role: models.RoleUser, EmailVerified: true,
+			Version: 1, CreatedAt: exportedAt, DeletedAt: &exportedAt},
+	}
+	mockRepo.EXPECT().ExportUsers(filter, gomock.Any()).DoAndReturn(func(filter models.UserFilter, each func(models.User) error) error {
+		for _, user := range users {
+			if err := each(user); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func TestExportUsers_CSV(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+	filter := models.UserFilter{EmailDomain: "gmail.com", IncludeDeleted: true}
+
+	// Mock repository behavior
+	exportTestUsers(mockRepo, filter)
+
+	// Call the method
+	var out bytes.Buffer
+	export, err := NewUserExportWriter(ExportFormatCSV, &out)
+	assert.NoError(t, err)
+	err = service.ExportUsers(filter, export)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, "id,name,email,role,email_verified,version,created_at,deleted_at\n"+
+		"1,John,john@gmail.com,admin,false,2,2024-01-02T03:04:05.000006Z,\n"+
+		"2,\"Doe, Jane\",jane@gmail.com,user,true,1,2024-01-02T03:04:05.000006Z,2024-01-02T03:04:05.000006Z\n", out.String())
+}
+
+func TestExportUsers_CSVEscapesFormulas(t *testing.T) {
+	// Call the method
+	var out bytes.Buffer
+	export, err := NewUserExportWriter(ExportFormatCSV, &out)
+	assert.NoError(t, err)
+	for i, name := range []string{"=HYPERLINK(\"http://evil\")", "+1", "-1", "@SUM(A1)", "John-Doe"} {
+		assert.NoError(t, export.Write(models.UserExportRecord{ID: i + 1, Name: name, Email: "john@gmail.com", Role: models.RoleUser}))
+	}
+	assert.NoError(t, export.Close())
+
+	// Assertions
+	lines := strings.Split(out.String(), "\n")
+	assert.Contains(t, lines[1], `"'=HYPERLINK(""http://evil"")"`)
+	assert.Contains(t, lines[2], ",'+1,")
+	assert.Contains(t, lines[3], ",'-1,")
+	assert.Contains(t, lines[4], ",'@SUM(A1),")
+	assert.Contains(t, lines[5], ",John-Doe,")
+}
+
+func TestExportUsers_NDJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	// Mock repository behavior
+	exportTestUsers(mockRepo, models.UserFilter{})
+
+	// Call the method
+	var out bytes.Buffer
+	export, _ := NewUserExportWriter(ExportFormatNDJSON, &out)
+	err := service.ExportUsers(models.UserFilter{}, export)
+
+	// Assertions
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Len(t, lines, 2)
+	assert.JSONEq(t, `{"id":1,"name":"John","email":"john@gmail.com","role":"admin","email_verified":false,"version":2,
+		"created_at":"2024-01-02T03:04:05.000006Z","deleted_at":null}`, lines[0])
+	assert.NotContains(t, out.String(), "secret")
+}
+
+func TestExportUsers_Parquet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	// Mock repository behavior
+	exportTestUsers(mockRepo, models.UserFilter{})
+
+	// Call the method
+	var out bytes.Buffer
+	export, _ := NewUserExportWriter(ExportFormatParquet, &out)
+	err := service.ExportUsers(models.UserFilter{}, export)
+
+	// Assertions
+	assert.NoError(t, err)
+	records, err := parquet.Read[models.UserExportRecord](bytes.NewReader(out.Bytes()), int64(out.Len()))
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "Doe, Jane", records[1].Name)
+	assert.True(t, records[1].CreatedAt.Equal(exportedAt))
+	assert.Nil(t, records[0].DeletedAt)
+	assert.True(t, records[1].DeletedAt.Equal(exportedAt))
+	assert.NotContains(t, out.String(), "secret")
+}
+
+func TestNewUserExportWriter_UnknownFormat(t *testing.T) {
+	_, err := NewUserExportWriter("xlsx", &bytes.Buffer{})
+
+	assert.ErrorIs(t, err, ErrUnknownExportFormat)
+}
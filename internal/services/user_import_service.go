@@ -0,0 +1,361 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"io"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// DefaultUserImportBatchSize is the number of users inserted per transaction.
+const DefaultUserImportBatchSize = 500
+
+// maxImportLineSize limits the length of NDJSON import lines.
+const maxImportLineSize = 64 * 1024
+
+var (
+	ErrImportHeader   = errors.New("CSV header must name the name, email and password columns")
+	ErrDuplicateEmail = errors.New("email address appears more than once in the import")
+	ErrImportRead     = errors.New("error reading import")
+)
+
+// UserImportRow is one row read from an import.
+type UserImportRow struct {
+	Line   int
+	Record models.UserImportRecord
+	Err    error // Set when the row couldn't be parsed
+}
+
+// UserImportSource reads the rows of an import.
+type UserImportSource interface {
+	// Next returns the next row, or io.EOF after the last one. Rows that can't
+	// be parsed are returned with their error, and reading can go on.
+	Next() (UserImportRow, error)
+}
+
+type csvUserImportSource struct {
+	reader  *csv.Reader
+	columns map[string]int
+	fields  int
+}
+
+// NewCSVUserImportSource reads an import from CSV. The header row names the
+// columns, in any order: name, email, password and optionally role. Other
+// columns are ignored.
+func NewCSVUserImportSource(r io.Reader) (UserImportSource, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrImportHeader
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrImportHeader, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if _, ok := columns[column]; ok {
+			return nil, fmt.Errorf("%w: duplicate column %q", ErrImportHeader, column)
+		}
+		columns[column] = i
+	}
+	for _, required := range []string{"name", "email", "password"} {
+		if _, ok := columns[required]; !ok {
+			return nil, ErrImportHeader
+		}
+	}
+	return &csvUserImportSource{reader: reader, columns: columns, fields: len(header)}, nil
+}
+
+func (s *csvUserImportSource) Next() (UserImportRow, error) {
+	record, err := s.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return UserImportRow{Line: parseErr.StartLine, Err: err}, nil
+		}
+		return UserImportRow{}, err
+	}
+
+	line, _ := s.reader.FieldPos(0)
+	if len(record) != s.fields {
+		return UserImportRow{Line: line, Err: fmt.Errorf("expected %d fields, got %d", s.fields, len(record))}, nil
+	}
+	field := func(column string) string {
+		if i, ok := s.columns[column]; ok {
+			return record[i]
+		}
+		return ""
+	}
+	return UserImportRow{Line: line, Record: models.UserImportRecord{
+		Name:     strings.TrimSpace(field("name")),
+		Email:    strings.TrimSpace(field("email")),
+		Password: field("password"),
+		Role:     strings.TrimSpace(field("role")),
+	}}, nil
+}
+
+type ndjsonUserImportSource struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewNDJSONUserImportSource reads an import from newline-delimited JSON, one
+// models.UserImportRecord object per line. Blank lines are skipped.
+func NewNDJSONUserImportSource(r io.Reader) UserImportSource {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), maxImportLineSize)
+	return &ndjsonUserImportSource{scanner: scanner}
+}
+
+func (s *ndjsonUserImportSource) Next() (UserImportRow, error) {
+	for s.scanner.Scan() {
+		s.line++
+		line := bytes.TrimSpace(s.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		row := UserImportRow{Line: s.line}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.Record); err != nil {
+			row.Err = fmt.Errorf("invalid JSON: %v", err)
+		}
+		return row, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return UserImportRow{}, err
+	}
+	return UserImportRow{}, io.EOF
+}
+
+// UserImportService creates users in bulk.
+type UserImportService struct {
+	Users     repositories.UserRepositoryInterface
+	Passwords *PasswordPolicyService
+	Workers   int // Number of goroutines hashing passwords
+	BatchSize int // Number of users inserted per transaction
+}
+
+func NewUserImportService(users repositories.UserRepositoryInterface, passwords *PasswordPolicyService) *UserImportService {
+	return &UserImportService{Users: users, Passwords: passwords, Workers: runtime.NumCPU(), BatchSize: DefaultUserImportBatchSize}
+}
+
+// Import creates the users read from the source and reports the outcome of
+// every row. Rows are validated like POST /users requests and invalid ones
+// are skipped; the others are inserted in batches of BatchSize, one
+// transaction each. A dry run validates the rows without creating any user.
+//
+// If the source can't be read (ErrImportRead) or the database fails, Import
+// stops and returns the error with the report of the rows processed so far.
+// Importing again is safe: rows created before then fail as their email
+// address is taken.
+func (s *UserImportService) Import(source UserImportSource, dryRun bool) (models.UserImportReport, error) {
+	report := models.UserImportReport{DryRun: dryRun, Results: []models.UserImportResult{}}
+	seen := make(map[string]bool)
+
+	for {
+		var batch []UserImportRow
+		var readErr error
+		for len(batch) < max(s.BatchSize, 1) {
+			var row UserImportRow
+			if row, readErr = source.Next(); readErr != nil {
+				break
+			}
+			batch = append(batch, row)
+		}
+
+		results, err := s.importBatch(batch, seen, dryRun)
+		for _, result := range results {
+			report.Rows++
+			switch result.Status {
+			case models.ImportRowCreated, models.ImportRowValid:
+				report.Succeeded++
+			case models.ImportRowFailed:
+				report.Failed++
+			}
+		}
+		report.Results = append(report.Results, results...)
+
+		switch {
+		case err != nil:
+			return report, err
+		case errors.Is(readErr, io.EOF):
+			return report, nil
+		case readErr != nil:
+			return report, fmt.Errorf("%w: %w", ErrImportRead, readErr)
+		}
+	}
+}
+
+func (s *UserImportService) importBatch(rows []UserImportRow, seen map[string]bool, dryRun bool) ([]models.UserImportResult, error) {
+	results := make([]models.UserImportResult, len(rows))
+	users := make([]models.User, len(rows))
+	var pending []int // Indexes of the rows still to import
+
+	for i, row := range rows {
+		results[i] = models.UserImportResult{Line: row.Line, Email: row.Record.Email}
+		if row.Err != nil {
+			failImportRow(&results[i], row.Err)
+			continue
+		}
+		user := row.Record.User()
+		if err := s.validate(user); err != nil {
+			failImportRow(&results[i], err)
+			continue
+		}
+		if seen[user.Email] {
+			failImportRow(&results[i], ErrDuplicateEmail)
+			continue
+		}
+		seen[user.Email] = true
+		users[i] = user
+		pending = append(pending, i)
+	}
+
+	pending, err := s.skipTakenEmails(users, pending, results)
+	if err != nil || len(pending) == 0 {
+		return results, err
+	}
+	if dryRun {
+		for _, i := range pending {
+			results[i].Status = models.ImportRowValid
+		}
+		return results, nil
+	}
+
+	for i, err := range s.hashPasswords(users, pending) {
+		failImportRow(&results[i], err)
+	}
+	pending = slices.DeleteFunc(pending, func(i int) bool { return results[i].Status == models.ImportRowFailed })
+
+	for retried := false; len(pending) > 0; retried = true {
+		batch := make([]models.User, len(pending))
+		for j, i := range pending {
+			batch[j] = users[i]
+		}
+		ids, err := s.Users.ImportUsers(batch)
+		if errors.Is(err, repositories.ErrEmailInUse) && !retried {
+			// Another request took an email address since it was checked
+			if pending, err = s.skipTakenEmails(users, pending, results); err != nil {
+				return results, err
+			}
+			continue
+		}
+		if err != nil {
+			return results, err
+		}
+
+		for _, i := range pending {
+			results[i].Status = models.ImportRowCreated
+			results[i].ID = ids[users[i].Email]
+		}
+		break
+	}
+	return results, nil
+}
+
+// validate checks the user like a POST /users request.
+func (s *UserImportService) validate(user models.User) error {
+	if err := models.Validate.Struct(user); err != nil {
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			return err
+		}
+		fields := make([]string, len(validationErrors))
+		for i, e := range validationErrors {
+			fields[i] = importFieldName(e.Field()) + " (" + e.Tag() + ")"
+		}
+		return fmt.Errorf("validation failed: %s", strings.Join(fields, ", "))
+	}
+	if s.Passwords != nil {
+		return s.Passwords.Check(user.PasswordHash, user, "")
+	}
+	return nil
+}
+
+// importFieldName returns the import column of a User field.
+func importFieldName(field string) string {
+	if field == "PasswordHash" {
+		return "password"
+	}
+	return strings.ToLower(field)
+}
+
+// skipTakenEmails fails the pending rows whose email address is already in
+// use, and returns the remaining ones.
+func (s *UserImportService) skipTakenEmails(users []models.User, pending []int, results []models.UserImportResult) ([]int, error) {
+	if len(pending) == 0 {
+		return pending, nil
+	}
+	emails := make([]string, len(pending))
+	for j, i := range pending {
+		emails[j] = users[i].Email
+	}
+	existing, err := s.Users.ExistingEmails(emails)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(pending, func(i int) bool {
+		if !slices.Contains(existing, users[i].Email) {
+			return false
+		}
+		failImportRow(&results[i], repositories.ErrEmailInUse)
+		return true
+	}), nil
+}
+
+// hashPasswords replaces the plain-text passwords of the pending users with
+// their hashes, using Workers goroutines. It returns the errors of the users
+// whose password couldn't be hashed, by index.
+func (s *UserImportService) hashPasswords(users []models.User, pending []int) map[int]error {
+	jobs := make(chan int)
+	failed := make(map[int]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for w := 0; w < max(s.Workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				hash, err := utils.HashPassword(users[i].PasswordHash)
+				if err != nil {
+					mu.Lock()
+					failed[i] = err
+					mu.Unlock()
+					continue
+				}
+				users[i].PasswordHash = hash
+			}
+		}()
+	}
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return failed
+}
+
+func failImportRow(result *models.UserImportResult, err error) {
+	result.Status = models.ImportRowFailed
+	result.Error = err.Error()
+}
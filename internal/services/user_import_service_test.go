@@ -0,0 +1,164 @@
+package services
+
+import (
+	"go-crud/internal/models"
+	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVUserImportSource(t *testing.T) {
+	input := "Email,name,password,notes\n" +
+		"john@gmail.com,John,secret-password,first\n" +
+		"\n" +
+		"jane@gmail.com,Jane\n" +
+		"\"joe@gmail.com\",\"Joe, Jr.\",\"pass,word\",\n"
+
+	source, err := NewCSVUserImportSource(strings.NewReader(input))
+	assert.NoError(t, err)
+
+	row, err := source.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, UserImportRow{Line: 2, Record: models.UserImportRecord{Name: "John", Email: "john@gmail.com", Password: "secret-password"}}, row)
+
+	row, err = source.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, row.Line)
+	assert.Error(t, row.Err)
+
+	row, err = source.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, models.UserImportRecord{Name: "Joe, Jr.", Email: "joe@gmail.com", Password: "pass,word"}, row.Record)
+
+	_, err = source.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestCSVUserImportSource_MissingColumn(t *testing.T) {
+	_, err := NewCSVUserImportSource(strings.NewReader("name,email\nJohn,john@gmail.com\n"))
+
+	assert.ErrorIs(t, err, ErrImportHeader)
+}
+
+func TestNDJSONUserImportSource(t *testing.T) {
+	input := `{"name":"John","email":"john@gmail.com","password":"secret-password","role":"admin"}` + "\n\n" +
+		`{"name":"Jane","nickname":"JJ"}` + "\n"
+
+	source := NewNDJSONUserImportSource(strings.NewReader(input))
+
+	row, err := source.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, UserImportRow{Line: 1, Record: models.UserImportRecord{Name: "John", Email: "john@gmail.com", Password: "secret-password", Role: "admin"}}, row)
+
+	row, err = source.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, row.Line)
+	assert.Error(t, row.Err)
+
+	_, err = source.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestImport_CreatesValidRows(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserImportService(mockRepo, nil)
+	service.BatchSize = 2
+
+	input := "name,email,password\n" +
+		"John,john@gmail.com,secret-password\n" +
+		"J,invalid,short\n" +
+		"Jane,jane@gmail.com,secret-password\n" +
+		"Joe,john@gmail.com,secret-password\n" +
+		"Jim,jim@gmail.com,secret-password\n"
+	source, _ := NewCSVUserImportSource(strings.NewReader(input))
+
+	// Mock repository behavior
+	mockRepo.EXPECT().ExistingEmails([]string{"john@gmail.com"}).Return(nil, nil)
+	mockRepo.EXPECT().ImportUsers(gomock.Any()).DoAndReturn(func(users []models.User) (map[string]int, error) {
+		assert.Len(t, users, 1)
+		assert.NoError(t, utils.VerifyPassword(users[0].PasswordHash, "secret-password"))
+		return map[string]int{"john@gmail.com": 1}, nil
+	})
+	mockRepo.EXPECT().ExistingEmails([]string{"jane@gmail.com"}).Return([]string{"jane@gmail.com"}, nil)
+	mockRepo.EXPECT().ExistingEmails([]string{"jim@gmail.com"}).Return(nil, nil)
+	mockRepo.EXPECT().ImportUsers(gomock.Any()).Return(map[string]int{"jim@gmail.com": 2}, nil)
+
+	// Call the method
+	report, err := service.Import(source, false)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 5, report.Rows)
+	assert.Equal(t, 2, report.Succeeded)
+	assert.Equal(t, 3, report.Failed)
+	assert.Equal(t, models.UserImportResult{Line: 2, Email: "john@gmail.com", Status: models.ImportRowCreated, ID: 1}, report.Results[0])
+	assert.Equal(t, models.ImportRowFailed, report.Results[1].Status)
+	assert.Contains(t, report.Results[1].Error, "email")
+	assert.Equal(t, repositories.ErrEmailInUse.Error(), report.Results[2].Error)
+	assert.Equal(t, ErrDuplicateEmail.Error(), report.Results[3].Error)
+	assert.Equal(t, models.UserImportResult{Line: 6, Email: "jim@gmail.com", Status: models.ImportRowCreated, ID: 2}, report.Results[4])
+}
+
+func TestImport_DryRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserImportService(mockRepo, nil)
+
+	input := `{"name":"John","email":"john@gmail.com","password":"secret-password"}` + "\n" +
+		`{"name":"Jane","email":"jane@gmail.com","password":"secret-password","role":"owner"}` + "\n"
+
+	// Mock repository behavior: nothing is written
+	mockRepo.EXPECT().ExistingEmails([]string{"john@gmail.com"}).Return(nil, nil)
+
+	// Call the method
+	report, err := service.Import(NewNDJSONUserImportSource(strings.NewReader(input)), true)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Succeeded)
+	assert.Equal(t, models.ImportRowValid, report.Results[0].Status)
+	assert.Equal(t, models.ImportRowFailed, report.Results[1].Status)
+	assert.Contains(t, report.Results[1].Error, "role")
+}
+
+func TestImport_RetriesWhenEmailTaken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserImportService(mockRepo, nil)
+
+	input := "name,email,password\nJohn,john@gmail.com,secret-password\nJane,jane@gmail.com,secret-password\n"
+	source, _ := NewCSVUserImportSource(strings.NewReader(input))
+
+	// Mock repository behavior: jane@gmail.com signs up after the check
+	emails := []string{"john@gmail.com", "jane@gmail.com"}
+	gomock.InOrder(
+		mockRepo.EXPECT().ExistingEmails(emails).Return(nil, nil),
+		mockRepo.EXPECT().ImportUsers(gomock.Len(2)).Return(nil, repositories.ErrEmailInUse),
+		mockRepo.EXPECT().ExistingEmails(emails).Return([]string{"jane@gmail.com"}, nil),
+		mockRepo.EXPECT().ImportUsers(gomock.Len(1)).Return(map[string]int{"john@gmail.com": 1}, nil),
+	)
+
+	// Call the method
+	report, err := service.Import(source, false)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, models.ImportRowCreated, report.Results[0].Status)
+	assert.Equal(t, repositories.ErrEmailInUse.Error(), report.Results[1].Error)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"go-crud/internal/repositories"
+	"log"
+	"time"
+)
+
+// Defaults for purging deleted users.
+const (
+	DefaultUserRetention     = 30 * 24 * time.Hour
+	DefaultUserPurgeInterval = time.Hour
+)
+
+// UserPurgeService permanently deletes users once they have been soft-deleted
+// for longer than the retention period.
+type UserPurgeService struct {
+	Users     repositories.UserRepositoryInterface
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+func NewUserPurgeService(users repositories.UserRepositoryInterface, retention time.Duration) *UserPurgeService {
+	return &UserPurgeService{Users: users, Retention: retention, Interval: DefaultUserPurgeInterval}
+}
+
+// PurgeExpired permanently deletes the users whose retention period is over
+// and returns how many were removed.
+func (s *UserPurgeService) PurgeExpired() (int64, error) {
+	return s.Users.PurgeDeletedUsers(time.Now().Add(-s.Retention))
+}
+
+// Run purges expired users every Interval until ctx is done. Failures are
+// logged and retried on the next run.
+func (s *UserPurgeService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.PurgeExpired()
+		if err != nil {
+			log.Printf("Error purging deleted users: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted users", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
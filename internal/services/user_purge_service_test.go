@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-crud/internal/repositories"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeExpired_UsesRetention(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserPurgeService(mockRepo, 7*24*time.Hour)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().PurgeDeletedUsers(gomock.Any()).DoAndReturn(func(before time.Time) (int64, error) {
+		assert.WithinDuration(t, time.Now().Add(-7*24*time.Hour), before, time.Minute)
+		return 3, nil
+	})
+
+	// Call the method
+	purged, err := service.PurgeExpired()
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), purged)
+}
+
+func TestPurgeRun_StopsWithContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserPurgeService(mockRepo, time.Hour)
+	service.Interval = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	purges := 0
+
+	// Mock repository behavior: failures don't stop the job
+	mockRepo.EXPECT().PurgeDeletedUsers(gomock.Any()).DoAndReturn(func(time.Time) (int64, error) {
+		purges++
+		if purges == 3 {
+			cancel()
+		}
+		return 0, errors.New("database error")
+	}).MinTimes(3)
+
+	// Call the method
+	service.Run(ctx)
+
+	// Assertions
+	assert.GreaterOrEqual(t, purges, 3)
+}
@@ -1,32 +1,154 @@
 package services
 
 import (
-	_ "errors"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"go-crud/internal/models"
 	"go-crud/internal/repositories"
 	"go-crud/internal/utils"
+	"html"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidPassword    = errors.New("current password is incorrect")
+	ErrInvalidCursor      = errors.New("invalid or mismatched pagination cursor")
+	ErrCursorWithOffset   = errors.New("cursor and offset can't be combined")
+	ErrSearchTooShort     = errors.New("search query must be at least 2 characters")
+	ErrReadOnlyField      = errors.New("only name and email can be patched")
+	ErrInvalidPatchedUser = errors.New("patched document is not a valid user")
+	// ErrConcurrentUpdate means an update without If-Match lost the race with
+	// other writes twice in a row.
+	ErrConcurrentUpdate = errors.New("user is being modified concurrently, retry the request")
 )
 
 type NewMockUserServiceInterface interface {
 }
 
 type UserService struct {
-	Repo repositories.UserRepositoryInterface
+	Repo      repositories.UserRepositoryInterface
+	Passwords *PasswordPolicyService // Optional; enforces the password policy when set
+	// Optional; asks users to verify a changed email address when set
+	Verifications *EmailVerificationService
 }
 
-func NewUserService(repo repositories.UserRepositoryInterface) *UserService {
-	return &UserService{Repo: repo}
+func NewUserService(repo repositories.UserRepositoryInterface, passwords *PasswordPolicyService) *UserService {
+	return &UserService{Repo: repo, Passwords: passwords}
 }
 
 func (s *UserService) GetAllUsers() ([]models.User, error) {
 	return s.Repo.GetAllUsers()
 }
 
+// ListUsers returns one page of users matching the query. The page's
+// NextCursor continues the listing with the same sort order, and is only
+// valid for that order.
+func (s *UserService) ListUsers(query models.ListUsersQuery) (models.UserPage, error) {
+	params := models.UserListParams{
+		UserFilter: query.UserFilter,
+		Sort:       query.Sort,
+		Descending: query.Order == "desc",
+		Limit:      query.Limit,
+		Offset:     query.Offset,
+	}
+	if params.Sort == "" {
+		params.Sort = models.SortByID
+	}
+	if params.Limit == 0 {
+		params.Limit = models.DefaultUserPageSize
+	}
+
+	if query.Cursor != "" {
+		if query.Offset != 0 {
+			return models.UserPage{}, ErrCursorWithOffset
+		}
+		cursor, err := decodeUserCursor(query.Cursor)
+		if err != nil || cursor.Sort != params.Sort || cursor.Descending != params.Descending {
+			return models.UserPage{}, ErrInvalidCursor
+		}
+		params.After = &cursor
+	}
+
+	// Fetch one extra user to learn whether there is a next page
+	params.Limit++
+	users, err := s.Repo.ListUsers(params)
+	if err != nil {
+		return models.UserPage{}, err
+	}
+
+	page := models.UserPage{Users: users}
+	if len(users) == params.Limit {
+		page.Users = users[:len(users)-1]
+		last := page.Users[len(page.Users)-1]
+		page.NextCursor = encodeUserCursor(models.UserCursor{
+			Sort:       params.Sort,
+			Descending: params.Descending,
+			Value:      userSortValue(last, params.Sort),
+			ID:         last.ID,
+		})
+	}
+
+	if query.IncludeTotal {
+		total, err := s.Repo.CountUsers(query.UserFilter)
+		if err != nil {
+			return models.UserPage{}, err
+		}
+		page.Total = &total
+	}
+	return page, nil
+}
+
+// SearchUsers finds users by partial name or email, best matches first, and
+// highlights the matching parts.
+func (s *UserService) SearchUsers(query string, limit int) ([]models.UserSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if utf8.RuneCountInString(query) < 2 {
+		return nil, ErrSearchTooShort
+	}
+	if limit <= 0 {
+		limit = models.DefaultUserSearchLimit
+	}
+
+	results, err := s.Repo.Search(query, min(limit, models.MaxUserSearchLimit))
+	if err != nil {
+		return nil, err
+	}
+
+	terms := strings.Fields(query)
+	for i := range results {
+		results[i].Highlight = models.UserHighlight{
+			Name:  highlight(results[i].Name, terms),
+			Email: highlight(results[i].Email, terms),
+		}
+	}
+	return results, nil
+}
+
 func (s *UserService) GetUserByID(id int) (models.User, error) {
 	return s.Repo.GetUserByID(id)
 }
 
+// GetUserByIDIncludingDeleted also returns users that were deleted but not
+// purged yet.
+func (s *UserService) GetUserByIDIncludingDeleted(id int) (models.User, error) {
+	return s.Repo.GetUserByIDIncludingDeleted(id)
+}
+
 func (s *UserService) CreateUser(user models.User) (models.User, error) {
+	if s.Passwords != nil {
+		if err := s.Passwords.Check(user.PasswordHash, user, ""); err != nil {
+			return models.User{}, err
+		}
+	}
+
 	id, err := s.Repo.CreateUser(user)
 	if err != nil {
 		return models.User{}, err
@@ -35,33 +157,343 @@ func (s *UserService) CreateUser(user models.User) (models.User, error) {
 	return user, nil
 }
 
-func (s *UserService) UpdateUser(id int, req models.UpdateUserRequest) error {
+// UpdateUser partially updates the user and returns it at its new version.
+// ifMatch lists the versions the caller accepts to overwrite, nil meaning
+// any. The update fails with repositories.ErrVersionConflict if the user is
+// at another version, or changes before it is saved. Without ifMatch, an
+// update that lost such a race is retried once.
+func (s *UserService) UpdateUser(id int, req models.UpdateUserRequest, ifMatch []int) (models.User, error) {
+	return retryLostUpdate(ifMatch, func() (models.User, error) { return s.updateUser(id, req, ifMatch) })
+}
+
+func (s *UserService) updateUser(id int, req models.UpdateUserRequest, ifMatch []int) (models.User, error) {
 	user, err := s.Repo.GetUserByID(id)
 	if err != nil {
-		return err
+		return models.User{}, err
+	}
+	if !versionMatches(ifMatch, user.Version) {
+		return models.User{}, repositories.ErrVersionConflict
 	}
 
 	if req.Name != nil {
 		user.Name = *req.Name
 	}
+	emailChanged := req.Email != nil && *req.Email != user.Email
 	if req.Email != nil {
 		user.Email = *req.Email
 	}
+
+	// The update is conditional on the version read above, so concurrent
+	// updates can't silently overwrite each other
+	var currentHash string
 	if req.PasswordHash != nil {
-		hashedPassword, err := utils.HashPassword(*req.PasswordHash)
-		if err != nil {
-			return err
+		if currentHash, err = s.checkNewPassword(user, *req.PasswordHash); err != nil {
+			return models.User{}, err
+		}
+		if user.PasswordHash, err = utils.HashPassword(*req.PasswordHash); err != nil {
+			return models.User{}, err
+		}
+	}
+	if err := s.Repo.UpdateUser(id, user); err != nil {
+		return models.User{}, err
+	}
+	user.Version++
+	user.PasswordHash = ""
+	if emailChanged {
+		user.EmailVerified = false
+		s.verifyChangedEmail(user)
+	}
+
+	if req.PasswordHash != nil {
+		if err := s.rememberPassword(id, currentHash); err != nil {
+			return models.User{}, err
 		}
-		user.PasswordHash = hashedPassword
 	}
+	return user, nil
+}
+
+// DeleteUser soft-deletes the user. The account can be restored until it is
+// purged after the retention period. ifMatch is checked as for UpdateUser.
+func (s *UserService) DeleteUser(id int, ifMatch []int) error {
+	if ifMatch == nil {
+		return s.Repo.DeleteUser(id, 0)
+	}
+
+	user, err := s.Repo.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	if !versionMatches(ifMatch, user.Version) {
+		return repositories.ErrVersionConflict
+	}
+	return s.Repo.DeleteUser(id, user.Version)
+}
 
-	return s.Repo.UpdateUser(id, user)
+// ReplaceUser replaces the user's profile with the request. Unlike UpdateUser,
+// every profile field is required.
+func (s *UserService) ReplaceUser(id int, req models.ReplaceUserRequest, ifMatch []int) (models.User, error) {
+	if err := models.Validate.Struct(req); err != nil {
+		return models.User{}, err
+	}
+	return s.UpdateUser(id, models.UpdateUserRequest{Name: &req.Name, Email: &req.Email, PasswordHash: req.PasswordHash}, ifMatch)
 }
 
-func (s *UserService) DeleteUser(id int) error {
-	return s.Repo.DeleteUser(id)
+// PatchUser applies a patch to the user's JSON representation, validates the
+// result against the User validation rules and saves it. The patch is given as
+// the function applying it, such as utils.MergePatch or utils.ApplyJSONPatch.
+// ifMatch is checked, and lost races retried, as for UpdateUser.
+func (s *UserService) PatchUser(id int, patch func(document []byte) ([]byte, error), ifMatch []int) (models.User, error) {
+	return retryLostUpdate(ifMatch, func() (models.User, error) { return s.patchUser(id, patch, ifMatch) })
+}
+
+func (s *UserService) patchUser(id int, patch func(document []byte) ([]byte, error), ifMatch []int) (models.User, error) {
+	user, err := s.Repo.GetUserByID(id)
+	if err != nil {
+		return models.User{}, err
+	}
+	if !versionMatches(ifMatch, user.Version) {
+		return models.User{}, repositories.ErrVersionConflict
+	}
+
+	user.PasswordHash = ""
+	document, err := json.Marshal(user)
+	if err != nil {
+		return models.User{}, err
+	}
+	if document, err = patch(document); err != nil {
+		return models.User{}, err
+	}
+
+	var patched models.User
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return models.User{}, fmt.Errorf("%w: %v", ErrInvalidPatchedUser, err)
+	}
+	if !onlyProfileChanged(user, patched) {
+		return models.User{}, ErrReadOnlyField
+	}
+	if err := models.Validate.StructPartial(patched, "Name", "Email"); err != nil {
+		return models.User{}, err
+	}
+
+	// Like UpdateUser, the write is conditional on the version read above
+	if err := s.Repo.UpdateUser(id, patched); err != nil {
+		return models.User{}, err
+	}
+	patched.Version++
+	if patched.Email != user.Email {
+		patched.EmailVerified = false
+		s.verifyChangedEmail(patched)
+	}
+	return patched, nil
+}
+
+// verifyChangedEmail sends a verification email to the user's new address.
+// The change is already saved, so failures are only logged.
+func (s *UserService) verifyChangedEmail(user models.User) {
+	if s.Verifications == nil {
+		return
+	}
+	if err := s.Verifications.EmailChanged(user); err != nil {
+		log.Printf("Error sending verification email for changed address: %v", err)
+	}
+}
+
+// onlyProfileChanged reports whether the users differ in nothing but the
+// profile fields clients can write.
+func onlyProfileChanged(before, after models.User) bool {
+	return after.ID == before.ID && after.PasswordHash == before.PasswordHash && after.Role == before.Role &&
+		after.EmailVerified == before.EmailVerified && after.Version == before.Version &&
+		after.CreatedAt.Equal(before.CreatedAt) && after.DeletedAt == nil
+}
+
+// ExportUsers writes every user matching the filter to the export, in ID
+// order, and closes it.
+func (s *UserService) ExportUsers(filter models.UserFilter, export UserExportWriter) error {
+	err := s.Repo.ExportUsers(filter, func(user models.User) error {
+		return export.Write(models.NewUserExportRecord(user))
+	})
+	if err != nil {
+		return err
+	}
+	return export.Close()
+}
+
+// RestoreUser restores a deleted user that hasn't been purged yet.
+func (s *UserService) RestoreUser(id int) error {
+	return s.Repo.RestoreUser(id)
 }
 
 func (s *UserService) GetUserByEmail(email string) (models.User, error) {
 	return s.Repo.GetUserByEmail(email)
 }
+
+// ChangePassword replaces the user's password after verifying the current one.
+func (s *UserService) ChangePassword(id int, currentPassword, newPassword string) error {
+	passwordHash, err := s.Repo.GetPasswordHash(id)
+	if err != nil {
+		return err
+	}
+	if err := utils.VerifyPassword(passwordHash, currentPassword); err != nil {
+		return ErrInvalidPassword
+	}
+
+	if s.Passwords != nil {
+		user, err := s.Repo.GetUserByID(id)
+		if err != nil {
+			return err
+		}
+		if err := s.Passwords.Check(newPassword, user, passwordHash); err != nil {
+			return err
+		}
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.UpdatePassword(id, hashedPassword); err != nil {
+		return err
+	}
+	return s.rememberPassword(id, passwordHash)
+}
+
+// checkNewPassword enforces the password policy for a password replacing the
+// user's current one, and returns the current hash.
+func (s *UserService) checkNewPassword(user models.User, password string) (string, error) {
+	if s.Passwords == nil {
+		return "", nil
+	}
+	currentHash, err := s.Repo.GetPasswordHash(user.ID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.Passwords.Check(password, user, currentHash); err != nil {
+		return "", err
+	}
+	return currentHash, nil
+}
+
+// rememberPassword adds a replaced password hash to the password history.
+func (s *UserService) rememberPassword(id int, previousHash string) error {
+	if s.Passwords == nil {
+		return nil
+	}
+	return s.Passwords.Remember(id, previousHash)
+}
+
+// RehashPassword upgrades the stored hash of a password that was just verified
+// when it was produced with an outdated algorithm or parameters. A password
+// changed concurrently is left alone.
+func (s *UserService) RehashPassword(id int, passwordHash, password string) error {
+	if !utils.PasswordNeedsRehash(passwordHash) {
+		return nil
+	}
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.Repo.UpdatePasswordIfUnchanged(id, passwordHash, hashedPassword)
+}
+
+// userSortValue returns the value of the sort field of the user for a cursor.
+func userSortValue(user models.User, sort string) string {
+	switch sort {
+	case models.SortByName:
+		return user.Name
+	case models.SortByEmail:
+		return user.Email
+	case models.SortByCreatedAt:
+		return user.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		return strconv.Itoa(user.ID)
+	}
+}
+
+// encodeUserCursor encodes the cursor as an opaque URL-safe string.
+func encodeUserCursor(cursor models.UserCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeUserCursor(value string) (models.UserCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return models.UserCursor{}, err
+	}
+	var cursor models.UserCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return models.UserCursor{}, err
+	}
+	if cursor.ID <= 0 {
+		return models.UserCursor{}, ErrInvalidCursor
+	}
+	if cursor.Sort == models.SortByCreatedAt {
+		if _, err := time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return models.UserCursor{}, err
+		}
+	}
+	return cursor, nil
+}
+
+// highlight HTML-escapes text and wraps case-insensitive occurrences of the
+// terms in <mark> tags. Overlapping occurrences are merged.
+func highlight(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	marked := make([]bool, len(text))
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		// Lowercasing can change the byte length of some characters; skip
+		// highlighting rather than mark the wrong bytes
+		if term == "" || len(lower) != len(text) {
+			continue
+		}
+		for start := 0; ; {
+			i := strings.Index(lower[start:], term)
+			if i < 0 {
+				break
+			}
+			for j := start + i; j < start+i+len(term); j++ {
+				marked[j] = true
+			}
+			start += i + len(term)
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(text[i:j]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(text[i:j]))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// retryLostUpdate runs a read-modify-write update again when it lost the race
+// with another write although the caller accepts any version. A second loss
+// is reported as ErrConcurrentUpdate rather than as a failed precondition.
+func retryLostUpdate(ifMatch []int, update func() (models.User, error)) (models.User, error) {
+	user, err := update()
+	if ifMatch != nil || !errors.Is(err, repositories.ErrVersionConflict) {
+		return user, err
+	}
+	user, err = update()
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		return models.User{}, ErrConcurrentUpdate
+	}
+	return user, err
+}
+
+// versionMatches reports whether the version satisfies an If-Match
+// precondition; a nil list matches any version.
+func versionMatches(ifMatch []int, version int) bool {
+	return ifMatch == nil || slices.Contains(ifMatch, version)
+}
@@ -4,10 +4,14 @@ import (
 	"errors"
 	"go-crud/internal/models"
 	"go-crud/internal/repositories"
+	"go-crud/internal/utils"
 	"testing"
+	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestCreateUser_Success(t *testing.T) {
@@ -18,7 +22,7 @@ func TestCreateUser_Success(t *testing.T) {
 	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
 
 	// Create a service instance with the mock repository
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, nil)
 
 	// Test data
 	user := models.User{
@@ -51,7 +55,7 @@ func TestGetAllUsers_Success(t *testing.T) {
 	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
 
 	//Create a service instance with the mock repository
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, nil)
 
 	//Mock data
 	expectedUsers := []models.User{
@@ -79,7 +83,7 @@ func TestGetAllUsers_EmptyList(t *testing.T) {
 	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
 
 	// Create a service instance with the mock repository
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, nil)
 
 	// Mock repository behavior
 	mockRepo.EXPECT().GetAllUsers().Return([]models.User{}, nil)
@@ -101,7 +105,7 @@ func TestGetAllUsers_RepoError(t *testing.T) {
 	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
 
 	// Create a service instance with the mock repository
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, nil)
 
 	// Mock repository behavior
 	mockRepo.EXPECT().GetAllUsers().Return(nil, errors.New("database error"))
@@ -113,3 +117,553 @@ func TestGetAllUsers_RepoError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, result)
 }
+
+func TestChangePassword_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	currentHash, _ := utils.HashPassword("current-password")
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetPasswordHash(1).Return(currentHash, nil)
+	mockRepo.EXPECT().UpdatePassword(1, gomock.Any()).DoAndReturn(func(id int, passwordHash string) error {
+		assert.NoError(t, utils.VerifyPassword(passwordHash, "new-password"))
+		return nil
+	})
+
+	// Call the method
+	err := service.ChangePassword(1, "current-password", "new-password")
+
+	// Assertions
+	assert.NoError(t, err)
+}
+
+func TestChangePassword_WrongCurrentPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	currentHash, _ := utils.HashPassword("current-password")
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetPasswordHash(1).Return(currentHash, nil)
+
+	// Call the method
+	err := service.ChangePassword(1, "wrong-password", "new-password")
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+}
+
+func TestRehashPassword_UpgradesLegacyHash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	legacyHash, _ := utils.NewBcryptHasher(bcrypt.MinCost).Hash("password")
+
+	// Mock repository behavior
+	mockRepo.EXPECT().UpdatePasswordIfUnchanged(1, legacyHash, gomock.Any()).DoAndReturn(func(id int, oldHash, passwordHash string) error {
+		assert.False(t, utils.PasswordNeedsRehash(passwordHash))
+		assert.NoError(t, utils.VerifyPassword(passwordHash, "password"))
+		return nil
+	})
+
+	// Call the method
+	err := service.RehashPassword(1, legacyHash, "password")
+
+	// Assertions
+	assert.NoError(t, err)
+}
+
+func TestRehashPassword_KeepsCurrentHash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository; UpdatePasswordIfUnchanged must not be called
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	currentHash, _ := utils.HashPassword("password")
+
+	// Call the method
+	err := service.RehashPassword(1, currentHash, "password")
+
+	// Assertions
+	assert.NoError(t, err)
+}
+
+func TestListUsers_KeysetPagination(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	firstPage := []models.User{
+		{ID: 4, Name: "Alice"},
+		{ID: 2, Name: "Bob"},
+		{ID: 9, Name: "Carol"},
+	}
+
+	// Mock repository behavior: one extra user is fetched to detect the next page
+	mockRepo.EXPECT().ListUsers(models.UserListParams{Sort: models.SortByName, Limit: 3}).Return(firstPage, nil)
+	mockRepo.EXPECT().ListUsers(models.UserListParams{
+		Sort:  models.SortByName,
+		Limit: 3,
+		After: &models.UserCursor{Sort: models.SortByName, Value: "Bob", ID: 2},
+	}).Return(firstPage[2:], nil)
+
+	// Call the method
+	page, err := service.ListUsers(models.ListUsersQuery{Sort: models.SortByName, Limit: 2})
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, firstPage[:2], page.Users)
+	assert.NotEmpty(t, page.NextCursor)
+	assert.Nil(t, page.Total)
+
+	// Continue with the cursor
+	page, err = service.ListUsers(models.ListUsersQuery{Sort: models.SortByName, Limit: 2, Cursor: page.NextCursor})
+
+	assert.NoError(t, err)
+	assert.Equal(t, firstPage[2:], page.Users)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestListUsers_OffsetWithTotal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	filter := models.UserFilter{EmailDomain: "example.com"}
+
+	// Mock repository behavior
+	mockRepo.EXPECT().ListUsers(models.UserListParams{
+		UserFilter: filter,
+		Sort:       models.SortByCreatedAt,
+		Descending: true,
+		Limit:      models.DefaultUserPageSize + 1,
+		Offset:     40,
+	}).Return([]models.User{{ID: 1}}, nil)
+	mockRepo.EXPECT().CountUsers(filter).Return(41, nil)
+
+	// Call the method
+	page, err := service.ListUsers(models.ListUsersQuery{
+		UserFilter:   filter,
+		Sort:         models.SortByCreatedAt,
+		Order:        "desc",
+		Offset:       40,
+		IncludeTotal: true,
+	})
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+	assert.Empty(t, page.NextCursor)
+	assert.Equal(t, 41, *page.Total)
+}
+
+func TestListUsers_InvalidCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	// A cursor for another sort order can't be reused
+	cursor := encodeUserCursor(models.UserCursor{Sort: models.SortByName, Value: "Bob", ID: 2})
+
+	tests := []struct {
+		name  string
+		query models.ListUsersQuery
+		err   error
+	}{
+		{"garbage", models.ListUsersQuery{Cursor: "not a cursor"}, ErrInvalidCursor},
+		{"other sort", models.ListUsersQuery{Sort: models.SortByEmail, Cursor: cursor}, ErrInvalidCursor},
+		{"other order", models.ListUsersQuery{Sort: models.SortByName, Order: "desc", Cursor: cursor}, ErrInvalidCursor},
+		{"with offset", models.ListUsersQuery{Sort: models.SortByName, Cursor: cursor, Offset: 20}, ErrCursorWithOffset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ListUsers(tt.query)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository searching in memory
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+	search := repositories.NewInMemoryUserSearch([]models.User{
+		{ID: 1, Name: "Jonathan Smith", Email: "jsmith@example.com"},
+		{ID: 2, Name: "Jane Doe", Email: "jane@example.org"},
+		{ID: 3, Name: "<b>Joe</b>", Email: "joe@example.net"},
+	})
+	mockRepo.EXPECT().Search(gomock.Any(), gomock.Any()).DoAndReturn(search.Search).AnyTimes()
+
+	tests := []struct {
+		name  string
+		query string
+		ids   []int
+	}{
+		{"name prefix", "jona", []int{1}},
+		{"email substring", "smith@example", []int{1}},
+		{"typo", "jonathon", []int{1}},
+		{"several terms", "jane doe", []int{2}},
+		{"no match", "zebra", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Call the method
+			results, err := service.SearchUsers(tt.query, 0)
+
+			// Assertions
+			assert.NoError(t, err)
+			ids := []int{}
+			for _, result := range results {
+				ids = append(ids, result.ID)
+			}
+			assert.Equal(t, tt.ids, ids)
+		})
+	}
+}
+
+func TestSearchUsers_Highlight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository searching in memory
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+	search := repositories.NewInMemoryUserSearch([]models.User{{ID: 3, Name: "<b>Joe</b>", Email: "joe@example.net"}})
+	mockRepo.EXPECT().Search("joe", models.DefaultUserSearchLimit).DoAndReturn(search.Search)
+
+	// Call the method
+	results, err := service.SearchUsers("  joe ", 0)
+
+	// Assertions: matches are marked and the rest is escaped
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "&lt;b&gt;<mark>Joe</mark>&lt;/b&gt;", results[0].Highlight.Name)
+	assert.Equal(t, "<mark>joe</mark>@example.net", results[0].Highlight.Email)
+}
+
+func TestSearchUsers_TooShort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	// Call the method
+	_, err := service.SearchUsers(" j ", 0)
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrSearchTooShort)
+}
+
+func TestRestoreUser_EmailInUse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().RestoreUser(1).Return(repositories.ErrEmailInUse)
+
+	// Call the method
+	err := service.RestoreUser(1)
+
+	// Assertions
+	assert.ErrorIs(t, err, repositories.ErrEmailInUse)
+}
+
+func TestUpdateUser_IfMatchMismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	name := "Jane"
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Name: "John", Version: 3}, nil)
+
+	// Call the method
+	_, err := service.UpdateUser(1, models.UpdateUserRequest{Name: &name}, []int{2})
+
+	// Assertions
+	assert.ErrorIs(t, err, repositories.ErrVersionConflict)
+}
+
+func TestUpdateUser_BumpsVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	name := "Jane"
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Name: "John", Version: 3}, nil)
+	mockRepo.EXPECT().UpdateUser(1, models.User{ID: 1, Name: "Jane", Version: 3}).Return(nil)
+
+	// Call the method
+	user, err := service.UpdateUser(1, models.UpdateUserRequest{Name: &name}, []int{3})
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane", user.Name)
+	assert.Equal(t, 4, user.Version)
+}
+
+func TestUpdateUser_EmailChangeSendsVerification(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create mock repositories
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	mockTokens := repositories.NewMockActionTokenRepositoryInterface(ctrl)
+	mailer := &fakeMailer{}
+	service := NewUserService(mockRepo, nil)
+	service.Verifications = NewEmailVerificationService(mockTokens, mockRepo, mailer, "", true)
+
+	email := "jane@gmail.com"
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Email: "john@gmail.com", EmailVerified: true, Version: 3}, nil)
+	mockRepo.EXPECT().UpdateUser(1, gomock.Any()).Return(nil)
+	mockTokens.EXPECT().InvalidateActionTokens(1, models.PurposeEmailVerification).Return(nil)
+	mockTokens.EXPECT().CreateActionToken(gomock.Any()).Return(5, nil)
+
+	// Call the method
+	user, err := service.UpdateUser(1, models.UpdateUserRequest{Email: &email}, nil)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.False(t, user.EmailVerified)
+	assert.Equal(t, "jane@gmail.com", mailer.to)
+}
+
+func TestUpdateUser_ConcurrentUpdate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	name := "Jane"
+
+	// Mock repository behavior: the user changes between the read and the write
+	mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Name: "John", Version: 3}, nil)
+	mockRepo.EXPECT().UpdateUser(1, gomock.Any()).Return(repositories.ErrVersionConflict)
+
+	// Call the method
+	_, err := service.UpdateUser(1, models.UpdateUserRequest{Name: &name}, []int{3})
+
+	// Assertions
+	assert.ErrorIs(t, err, repositories.ErrVersionConflict)
+}
+
+func TestUpdateUser_RetriesLostRaceWithoutIfMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	name := "Jane"
+
+	// Mock repository behavior: the first write loses the race, the retry
+	// starts from the new version
+	gomock.InOrder(
+		mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Name: "John", Version: 3}, nil),
+		mockRepo.EXPECT().UpdateUser(1, gomock.Any()).Return(repositories.ErrVersionConflict),
+		mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Name: "John", Version: 4}, nil),
+		mockRepo.EXPECT().UpdateUser(1, models.User{ID: 1, Name: "Jane", Version: 4}).Return(nil),
+	)
+
+	// Call the method
+	user, err := service.UpdateUser(1, models.UpdateUserRequest{Name: &name}, nil)
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, 5, user.Version)
+}
+
+func TestUpdateUser_KeepsLosingRaceWithoutIfMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	name := "Jane"
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Name: "John", Version: 3}, nil).Times(2)
+	mockRepo.EXPECT().UpdateUser(1, gomock.Any()).Return(repositories.ErrVersionConflict).Times(2)
+
+	// Call the method
+	_, err := service.UpdateUser(1, models.UpdateUserRequest{Name: &name}, nil)
+
+	// Assertions
+	assert.ErrorIs(t, err, ErrConcurrentUpdate)
+}
+
+func TestDeleteUser_IfMatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Version: 3}, nil).Times(2)
+	mockRepo.EXPECT().DeleteUser(1, 3).Return(nil)
+
+	// Call the method with a stale and a current version
+	staleErr := service.DeleteUser(1, []int{2})
+	err := service.DeleteUser(1, []int{3})
+
+	// Assertions
+	assert.ErrorIs(t, staleErr, repositories.ErrVersionConflict)
+	assert.NoError(t, err)
+}
+
+func TestReplaceUser_RequiresEveryField(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	// Call the method without an email
+	_, err := service.ReplaceUser(1, models.ReplaceUserRequest{Name: "Jane"}, nil)
+
+	// Assertions
+	var validationErrors validator.ValidationErrors
+	assert.ErrorAs(t, err, &validationErrors)
+}
+
+func TestPatchUser_MergePatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	user := models.User{ID: 1, Name: "John", Email: "john@gmail.com", Role: models.RoleUser, Version: 3, CreatedAt: createdAt}
+	patch := func(document []byte) ([]byte, error) {
+		return utils.MergePatch(document, []byte(`{"name":"Jane"}`))
+	}
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetUserByID(1).Return(user, nil)
+	mockRepo.EXPECT().UpdateUser(1, gomock.Any()).DoAndReturn(func(id int, patched models.User) error {
+		assert.Equal(t, "Jane", patched.Name)
+		assert.Equal(t, "john@gmail.com", patched.Email)
+		assert.Equal(t, 3, patched.Version)
+		return nil
+	})
+
+	// Call the method
+	patched, err := service.PatchUser(1, patch, []int{3})
+
+	// Assertions
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane", patched.Name)
+	assert.Equal(t, 4, patched.Version)
+}
+
+func TestPatchUser_Rejected(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		err   error
+	}{
+		{"failed test", `[{"op":"test","path":"/name","value":"Jane"},{"op":"replace","path":"/name","value":"Joe"}]`, utils.ErrPatchTestFailed},
+		{"read-only field", `[{"op":"replace","path":"/role","value":"admin"}]`, ErrReadOnlyField},
+		{"password", `[{"op":"replace","path":"/passwordHash","value":"new-password"}]`, ErrReadOnlyField},
+		{"unknown field", `[{"op":"add","path":"/nickname","value":"JJ"}]`, ErrInvalidPatchedUser},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// Create a mock repository
+			mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+			service := NewUserService(mockRepo, nil)
+
+			// Mock repository behavior
+			mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Name: "John", Email: "john@gmail.com", Role: models.RoleUser, Version: 3}, nil)
+
+			// Call the method
+			_, err := service.PatchUser(1, func(document []byte) ([]byte, error) {
+				return utils.ApplyJSONPatch(document, []byte(tt.patch))
+			}, nil)
+
+			// Assertions
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestPatchUser_ClearedFieldFailsValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Create a mock repository
+	mockRepo := repositories.NewMockUserRepositoryInterface(ctrl)
+	service := NewUserService(mockRepo, nil)
+
+	// Mock repository behavior
+	mockRepo.EXPECT().GetUserByID(1).Return(models.User{ID: 1, Name: "John", Email: "john@gmail.com", Version: 3}, nil)
+
+	// Call the method
+	_, err := service.PatchUser(1, func(document []byte) ([]byte, error) {
+		return utils.MergePatch(document, []byte(`{"email":null}`))
+	}, nil)
+
+	// Assertions
+	var validationErrors validator.ValidationErrors
+	assert.ErrorAs(t, err, &validationErrors)
+}
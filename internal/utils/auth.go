@@ -1,46 +1,49 @@
 package utils
 
 import (
-	"errors"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"time"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
-// jwtSecret stores the loaded secret key
-var jwtKey []byte
-
-// SetJWTSecret sets the JWT secret key (called from main.go)
-func SetJWTSecret(secret string) {
-	jwtKey = []byte(secret)
-}
+const (
+	AccessTokenTTL   = 15 * time.Minute    // Default lifetime of access tokens
+	RefreshTokenTTL  = 30 * 24 * time.Hour // Lifetime of refresh tokens
+	MFAChallengeTTL  = 5 * time.Minute     // Default lifetime of MFA challenge tokens
+	ImpersonationTTL = 10 * time.Minute    // Default lifetime of impersonation tokens
+	DefaultClockSkew = 30 * time.Second    // Default leeway when checking exp, nbf and iat
+)
 
-// GenerateToken generates a JWT token for the given user ID.
-func GenerateToken(userID int) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
-	})
-	return token.SignedString(jwtKey)
-}
+// Values of the typ claim, so a token can't be used for another purpose than it was issued for.
+const (
+	TokenTypeAccess       = "access"
+	TokenTypeMFAChallenge = "mfa_challenge"
+)
 
-// ParseToken parses and validates a JWT token.
-func ParseToken(tokenString string) (int, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
-	})
-	if err != nil || !token.Valid {
-		return 0, errors.New("invalid token")
+// GenerateOpaqueToken returns a random URL-safe string suitable for refresh
+// tokens and other bearer secrets that are not JWTs.
+func GenerateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return 0, errors.New("invalid token claims")
+// generateTokenID returns a random identifier for the jti claim.
+func generateTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(b), nil
+}
 
-	userID, ok := claims["user_id"].(float64)
-	if !ok {
-		return 0, errors.New("invalid user ID in token")
-	}
-	return int(userID), nil
+// HashToken returns the hex-encoded SHA-256 of an opaque token. Opaque tokens
+// are high-entropy, so a fast hash is sufficient for storage and lookup.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BloomFilter is a probabilistic set of SHA-1 digests. Test never reports a
+// false negative, and reports a false positive with the configured rate.
+type BloomFilter struct {
+	bits   []uint64
+	m      uint64 // Number of bits
+	hashes uint64 // Number of bit positions per element
+}
+
+// NewBloomFilter sizes a filter for n elements with the false positive rate p.
+func NewBloomFilter(n int, p float64) *BloomFilter {
+	if n < 1 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	m = (m + 63) / 64 * 64
+	k := uint64(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &BloomFilter{bits: make([]uint64, m/64), m: m, hashes: k}
+}
+
+// Add inserts a SHA-1 digest.
+func (f *BloomFilter) Add(digest [sha1.Size]byte) {
+	h1, h2 := bloomHashes(digest)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Test reports whether the digest may be in the set.
+func (f *BloomFilter) Test(digest [sha1.Size]byte) bool {
+	h1, h2 := bloomHashes(digest)
+	for i := uint64(0); i < f.hashes; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHashes derives the two hashes for double hashing from the digest,
+// which is already uniformly distributed.
+func bloomHashes(digest [sha1.Size]byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(digest[0:8]), binary.BigEndian.Uint64(digest[8:16]) | 1
+}
+
+// BreachedPasswordFilter is a Bloom filter of the SHA-1 digests of passwords
+// known from data breaches, loaded from local files so that checking a
+// password never sends anything over the network.
+type BreachedPasswordFilter struct {
+	*BloomFilter
+}
+
+// Contains reports whether the password is (probably) in the breach corpus.
+func (f *BreachedPasswordFilter) Contains(password string) bool {
+	return f.Test(sha1.Sum([]byte(password)))
+}
+
+// LoadBreachedPasswordFilter loads SHA-1 digests in the Pwned Passwords
+// format, either a single file with one "<40 hex digits>[:count]" per line
+// or a directory of k-anonymity range files named by their 5 hex digit
+// prefix, each with "<35 hex digit suffix>[:count]" lines.
+func LoadBreachedPasswordFilter(path string, falsePositiveRate float64) (*BreachedPasswordFilter, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each source yields its digest prefix ("" for full digests) and file
+	type source struct{ prefix, path string }
+	var sources []source
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			prefix := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+			if entry.IsDir() || len(prefix) != 5 {
+				continue
+			}
+			sources = append(sources, source{strings.ToUpper(prefix), filepath.Join(path, entry.Name())})
+		}
+	} else {
+		sources = append(sources, source{"", path})
+	}
+
+	// The first pass only counts the entries so the filter can be sized for
+	// them, and the second adds them without holding every digest in memory
+	count := 0
+	for _, src := range sources {
+		err := scanDigestLines(src.path, func(line int, hash string) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filter := NewBloomFilter(count, falsePositiveRate)
+	for _, src := range sources {
+		err := scanDigestLines(src.path, func(line int, hash string) error {
+			var digest [sha1.Size]byte
+			decoded, err := hex.DecodeString(src.prefix + hash)
+			if err != nil || len(decoded) != sha1.Size {
+				return fmt.Errorf("line %d: invalid SHA-1 hash", line)
+			}
+			copy(digest[:], decoded)
+			filter.Add(digest)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &BreachedPasswordFilter{BloomFilter: filter}, nil
+}
+
+// scanDigestLines calls fn with the hash of every entry in the file, skipping
+// blank lines and comments.
+func scanDigestLines(path string, fn func(line int, hash string) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(text, ":")
+		if err := fn(line, hash); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
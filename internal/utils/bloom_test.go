@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestBloomFilter_NoFalseNegatives(t *testing.T) {
+	filter := NewBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		filter.Add(sha1.Sum([]byte(fmt.Sprint("member-", i))))
+	}
+
+	for i := 0; i < 1000; i++ {
+		assert.True(t, filter.Test(sha1.Sum([]byte(fmt.Sprint("member-", i)))))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if filter.Test(sha1.Sum([]byte(fmt.Sprint("other-", i)))) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 300)
+}
+
+func TestLoadBreachedPasswordFilter_HashList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	content := "# Top passwords\n" + sha1Hex("123456") + ":37359195\n" + strings.ToLower(sha1Hex("password")) + "\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	filter, err := LoadBreachedPasswordFilter(path, 0.001)
+
+	assert.NoError(t, err)
+	assert.True(t, filter.Contains("123456"))
+	assert.True(t, filter.Contains("password"))
+	assert.False(t, filter.Contains("correct horse battery staple"))
+}
+
+func TestLoadBreachedPasswordFilter_RangeFiles(t *testing.T) {
+	dir := t.TempDir()
+	hash := sha1Hex("qwerty")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, hash[:5]+".txt"), []byte(hash[5:]+":3912816\n"), 0o600))
+
+	filter, err := LoadBreachedPasswordFilter(dir, 0.001)
+
+	assert.NoError(t, err)
+	assert.True(t, filter.Contains("qwerty"))
+	assert.False(t, filter.Contains("123456"))
+}
+
+func TestLoadBreachedPasswordFilter_InvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("not-a-hash\n"), 0o600))
+
+	_, err := LoadBreachedPasswordFilter(path, 0.001)
+
+	assert.ErrorContains(t, err, "line 1")
+}
@@ -0,0 +1,251 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Errors returned when applying JSON patches.
+var (
+	// ErrInvalidPatch is returned for patch documents that aren't well-formed.
+	ErrInvalidPatch = errors.New("invalid patch document")
+	// ErrPatchConflict is returned when an operation refers to a location
+	// that doesn't exist in the document.
+	ErrPatchConflict = errors.New("patch can't be applied to the document")
+	// ErrPatchTestFailed is returned when a test operation doesn't match.
+	ErrPatchTestFailed = errors.New("patch test operation failed")
+)
+
+// Patch operations supported by ApplyJSONPatch.
+const (
+	PatchOpAdd     = "add"
+	PatchOpRemove  = "remove"
+	PatchOpReplace = "replace"
+	PatchOpTest    = "test"
+)
+
+// PatchOperation is one operation of an RFC 6902 JSON Patch.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to a JSON document: members
+// of the patch replace those of the document, recursively for objects, and
+// null members remove them.
+func MergePatch(document, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+	var changes interface{}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return json.Marshal(mergePatch(target, changes))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	changes, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(object, name)
+			continue
+		}
+		object[name] = mergePatch(object[name], value)
+	}
+	return object
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch to a JSON document. The add,
+// remove, replace and test operations are supported. The patch applies
+// atomically: if any operation fails, none of them do.
+func ApplyJSONPatch(document, patch []byte) ([]byte, error) {
+	var target interface{}
+	if err := json.Unmarshal(document, &target); err != nil {
+		return nil, err
+	}
+	var operations []PatchOperation
+	if err := json.Unmarshal(patch, &operations); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	for i, operation := range operations {
+		var err error
+		if target, err = applyPatchOperation(target, operation); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func applyPatchOperation(target interface{}, operation PatchOperation) (interface{}, error) {
+	tokens, err := parseJSONPointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch operation.Op {
+	case PatchOpAdd, PatchOpReplace, PatchOpTest:
+		if operation.Value == nil {
+			return nil, fmt.Errorf("%w: %s requires a value", ErrInvalidPatch, operation.Op)
+		}
+		if err := json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+	case PatchOpRemove:
+	default:
+		return nil, fmt.Errorf("%w: unsupported operation %q", ErrInvalidPatch, operation.Op)
+	}
+
+	// Operations on the whole document
+	if len(tokens) == 0 {
+		switch operation.Op {
+		case PatchOpRemove:
+			return nil, fmt.Errorf("%w: the document can't be removed", ErrPatchConflict)
+		case PatchOpTest:
+			if !reflect.DeepEqual(target, value) {
+				return nil, ErrPatchTestFailed
+			}
+			return target, nil
+		}
+		return value, nil
+	}
+
+	return updateAt(target, tokens, func(parent interface{}, key string) (interface{}, error) {
+		switch parent := parent.(type) {
+		case map[string]interface{}:
+			return patchObject(parent, key, operation.Op, value)
+		case []interface{}:
+			return patchArray(parent, key, operation.Op, value)
+		}
+		return nil, fmt.Errorf("%w: %s is not an object or array", ErrPatchConflict, operation.Path)
+	})
+}
+
+// updateAt walks the document to the container of the last pointer token and
+// replaces it with the result of update.
+func updateAt(target interface{}, tokens []string, update func(parent interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return update(target, tokens[0])
+	}
+
+	switch parent := target.(type) {
+	case map[string]interface{}:
+		value, ok := parent[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("%w: member %q not found", ErrPatchConflict, tokens[0])
+		}
+		child, err := updateAt(value, tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		parent[tokens[0]] = child
+		return parent, nil
+	case []interface{}:
+		index, err := arrayIndex(tokens[0], len(parent)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := updateAt(parent[index], tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		parent[index] = child
+		return parent, nil
+	}
+	return nil, fmt.Errorf("%w: %q is not an object or array", ErrPatchConflict, tokens[0])
+}
+
+func patchObject(object map[string]interface{}, name, op string, value interface{}) (interface{}, error) {
+	current, exists := object[name]
+	if !exists && op != PatchOpAdd {
+		return nil, fmt.Errorf("%w: member %q not found", ErrPatchConflict, name)
+	}
+
+	switch op {
+	case PatchOpAdd, PatchOpReplace:
+		object[name] = value
+	case PatchOpRemove:
+		delete(object, name)
+	case PatchOpTest:
+		if !reflect.DeepEqual(current, value) {
+			return nil, ErrPatchTestFailed
+		}
+	}
+	return object, nil
+}
+
+func patchArray(array []interface{}, token, op string, value interface{}) (interface{}, error) {
+	if op == PatchOpAdd {
+		// "-" appends to the array, and an index inserts before that element
+		index := len(array)
+		if token != "-" {
+			var err error
+			if index, err = arrayIndex(token, len(array)); err != nil {
+				return nil, err
+			}
+		}
+		array = append(array, nil)
+		copy(array[index+1:], array[index:])
+		array[index] = value
+		return array, nil
+	}
+
+	index, err := arrayIndex(token, len(array)-1)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case PatchOpReplace:
+		array[index] = value
+	case PatchOpRemove:
+		array = append(array[:index], array[index+1:]...)
+	case PatchOpTest:
+		if !reflect.DeepEqual(array[index], value) {
+			return nil, ErrPatchTestFailed
+		}
+	}
+	return array, nil
+}
+
+// arrayIndex parses an array index of a JSON pointer, which can't exceed max.
+func arrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || strconv.Itoa(index) != token {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrPatchConflict, token)
+	}
+	if index > max {
+		return 0, fmt.Errorf("%w: array index %d out of bounds", ErrPatchConflict, index)
+	}
+	return index, nil
+}
+
+// parseJSONPointer splits an RFC 6901 JSON pointer into its unescaped tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: invalid path %q", ErrInvalidPatch, pointer)
+	}
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = unescape.Replace(token)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	document := `{"name":"John","email":"john@gmail.com","tags":["a"],"address":{"city":"Paris","zip":"75001"}}`
+
+	patched, err := MergePatch([]byte(document), []byte(`{"name":"Jane","tags":null,"address":{"zip":null,"country":"FR"}}`))
+
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"Jane","email":"john@gmail.com","address":{"city":"Paris","country":"FR"}}`, string(patched))
+}
+
+func TestMergePatch_Invalid(t *testing.T) {
+	_, err := MergePatch([]byte(`{"name":"John"}`), []byte(`{"name":`))
+
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	document := `{"name":"John","version":3,"tags":["a","b"],"a/b":{"~c":1}}`
+
+	tests := []struct {
+		name     string
+		patch    string
+		expected string
+		err      error
+	}{
+		{
+			name:     "replace and add",
+			patch:    `[{"op":"test","path":"/version","value":3},{"op":"replace","path":"/name","value":"Jane"},{"op":"add","path":"/email","value":"jane@gmail.com"}]`,
+			expected: `{"name":"Jane","email":"jane@gmail.com","version":3,"tags":["a","b"],"a/b":{"~c":1}}`,
+		},
+		{
+			name:     "arrays",
+			patch:    `[{"op":"add","path":"/tags/-","value":"c"},{"op":"add","path":"/tags/0","value":"z"},{"op":"remove","path":"/tags/1"}]`,
+			expected: `{"name":"John","version":3,"tags":["z","b","c"],"a/b":{"~c":1}}`,
+		},
+		{
+			name:     "escaped pointer and null value",
+			patch:    `[{"op":"replace","path":"/a~1b/~0c","value":null},{"op":"remove","path":"/name"}]`,
+			expected: `{"version":3,"tags":["a","b"],"a/b":{"~c":null}}`,
+		},
+		{
+			name:  "failed test",
+			patch: `[{"op":"replace","path":"/name","value":"Jane"},{"op":"test","path":"/version","value":2}]`,
+			err:   ErrPatchTestFailed,
+		},
+		{
+			name:  "missing member",
+			patch: `[{"op":"replace","path":"/email","value":"jane@gmail.com"}]`,
+			err:   ErrPatchConflict,
+		},
+		{
+			name:  "index out of bounds",
+			patch: `[{"op":"remove","path":"/tags/2"}]`,
+			err:   ErrPatchConflict,
+		},
+		{
+			name:  "unsupported operation",
+			patch: `[{"op":"move","from":"/name","path":"/email"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "missing value",
+			patch: `[{"op":"add","path":"/email"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "not a patch",
+			patch: `{"op":"add","path":"/email","value":"jane@gmail.com"}`,
+			err:   ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patched, err := ApplyJSONPatch([]byte(document), []byte(tt.patch))
+
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(patched))
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKeyID       = errors.New("unknown key id")
+	ErrUnexpectedAlg      = errors.New("unexpected signing method")
+	ErrUnsupportedKeyType = errors.New("unsupported key type")
+)
+
+// verificationKey is a key accepted when verifying tokens, bound to the only
+// signing method it may be used with.
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    interface{}
+}
+
+// KeySet holds the key used to sign tokens and every key accepted when
+// verifying them, indexed by key ID (kid). Keeping retired public keys in the
+// set lets tokens signed before a key rotation stay valid until they expire.
+type KeySet struct {
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	signingKey    interface{}
+	verifyKeys    map[string]verificationKey
+}
+
+// NewHMACKeySet returns a key set that signs and verifies with a shared
+// HS256 secret. HMAC tokens carry no kid.
+func NewHMACKeySet(secret []byte) *KeySet {
+	return &KeySet{
+		signingMethod: jwt.SigningMethodHS256,
+		signingKey:    secret,
+		verifyKeys: map[string]verificationKey{
+			"": {method: jwt.SigningMethodHS256, key: secret},
+		},
+	}
+}
+
+// NewKeySet returns a key set that signs with an RSA (RS256) or Ed25519
+// (EdDSA) private key. When kid is empty, the RFC 7638 thumbprint is used.
+func NewKeySet(signingKey crypto.Signer, kid string) (*KeySet, error) {
+	method, err := signingMethodFor(signingKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	if kid == "" {
+		if kid, err = thumbprint(signingKey.Public()); err != nil {
+			return nil, err
+		}
+	}
+
+	ks := &KeySet{
+		signingKID:    kid,
+		signingMethod: method,
+		signingKey:    signingKey,
+		verifyKeys:    make(map[string]verificationKey),
+	}
+	if err := ks.AddVerificationKey(kid, signingKey.Public()); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// AddVerificationKey accepts tokens signed by the private half of publicKey.
+// When kid is empty, the RFC 7638 thumbprint is used.
+func (ks *KeySet) AddVerificationKey(kid string, publicKey crypto.PublicKey) error {
+	method, err := signingMethodFor(publicKey)
+	if err != nil {
+		return err
+	}
+	if kid == "" {
+		if kid, err = thumbprint(publicKey); err != nil {
+			return err
+		}
+	}
+	ks.verifyKeys[kid] = verificationKey{method: method, key: publicKey}
+	return nil
+}
+
+// Sign signs the claims with the current signing key and sets the kid header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signingMethod, claims)
+	if ks.signingKID != "" {
+		token.Header["kid"] = ks.signingKID
+	}
+	return token.SignedString(ks.signingKey)
+}
+
+// Keyfunc resolves the verification key for a token by its kid header and
+// rejects tokens whose alg doesn't match that key.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.verifyKeys[kid]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, ErrUnexpectedAlg
+	}
+	return key.key, nil
+}
+
+// JWK is a JSON Web Key (RFC 7517) holding a public key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // EC or OKP curve
+	X   string `json:"x,omitempty"`   // EC x coordinate or OKP public key
+	Y   string `json:"y,omitempty"`   // EC y coordinate
+}
+
+// PublicKey decodes an RSA, EC or Ed25519 public key, e.g. from the JWKS of
+// an external identity provider.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > math.MaxInt32 {
+			return nil, ErrUnsupportedKeyType
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, ErrUnsupportedKeyType
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, ErrUnsupportedKeyType
+		}
+		return key, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if k.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, ErrUnsupportedKeyType
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public verification keys. Shared HMAC secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for kid, key := range ks.verifyKeys {
+		jwk, err := toJWK(kid, key)
+		if err != nil {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// LoadSigningKeyFile reads a PEM-encoded RSA or Ed25519 private key
+// (PKCS #8, or PKCS #1 for RSA).
+func LoadSigningKeyFile(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, ErrUnsupportedKeyType
+		}
+		if _, err := signingMethodFor(signer.Public()); err != nil {
+			return nil, err
+		}
+		return signer, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%s: unable to parse private key", path)
+}
+
+// LoadVerificationKeyFile reads a PEM-encoded RSA or Ed25519 public key
+// (PKIX, PKCS #1 for RSA, or an X.509 certificate).
+func LoadVerificationKeyFile(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("%s: unable to parse public key", path)
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
+
+func signingMethodFor(publicKey crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, ErrUnsupportedKeyType
+	}
+}
+
+func toJWK(kid string, key verificationKey) (JWK, error) {
+	switch pub := key.key.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: key.method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: kid,
+			Use: "sig",
+			Alg: key.method.Alg(),
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(pub),
+		}, nil
+	default:
+		return JWK{}, ErrUnsupportedKeyType
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint of a public key.
+func thumbprint(publicKey crypto.PublicKey) (string, error) {
+	method, err := signingMethodFor(publicKey)
+	if err != nil {
+		return "", err
+	}
+	jwk, err := toJWK("", verificationKey{method: method, key: publicKey})
+	if err != nil {
+		return "", err
+	}
+
+	// Required members only, in lexicographic order
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{"user_id": 1, "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+func TestKeySet_SignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		alg  string
+	}{
+		{"RS256", rsaKey, "RS256"},
+		{"EdDSA", edKey, "EdDSA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, err := NewKeySet(tt.key, "")
+			assert.NoError(t, err)
+
+			signed, err := ks.Sign(testClaims())
+			assert.NoError(t, err)
+
+			token, err := jwt.Parse(signed, ks.Keyfunc)
+			assert.NoError(t, err)
+			assert.True(t, token.Valid)
+			assert.Equal(t, tt.alg, token.Method.Alg())
+			assert.NotEmpty(t, token.Header["kid"])
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	oldSet, err := NewKeySet(oldKey, "old")
+	assert.NoError(t, err)
+	signed, err := oldSet.Sign(testClaims())
+	assert.NoError(t, err)
+
+	// A key set without the retired key rejects the token
+	newSet, err := NewKeySet(newKey, "new")
+	assert.NoError(t, err)
+	_, err = jwt.Parse(signed, newSet.Keyfunc)
+	assert.ErrorIs(t, err, ErrUnknownKeyID)
+
+	// Once the retired public key is added, the token verifies again
+	assert.NoError(t, newSet.AddVerificationKey("old", &oldKey.PublicKey))
+	token, err := jwt.Parse(signed, newSet.Keyfunc)
+	assert.NoError(t, err)
+	assert.True(t, token.Valid)
+	assert.Len(t, newSet.JWKS().Keys, 2)
+}
+
+func TestKeySet_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ks, err := NewKeySet(rsaKey, "rsa")
+	assert.NoError(t, err)
+
+	// An HS256 token "signed" with the public key must not verify against it
+	pub, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	forged.Header["kid"] = "rsa"
+	signed, err := forged.SignedString(pub)
+	assert.NoError(t, err)
+
+	_, err = jwt.Parse(signed, ks.Keyfunc)
+	assert.ErrorIs(t, err, ErrUnexpectedAlg)
+}
+
+func TestKeySet_JWKSOmitsHMACSecrets(t *testing.T) {
+	assert.Empty(t, NewHMACKeySet([]byte("secret")).JWKS().Keys)
+}
+
+func TestLoadKeyFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	assert.NoError(t, err)
+	privPath := filepath.Join(dir, "signing.pem")
+	assert.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	der, err = x509.MarshalPKIXPublicKey(edKey.Public())
+	assert.NoError(t, err)
+	pubPath := filepath.Join(dir, "verify.pem")
+	assert.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+
+	signer, err := LoadSigningKeyFile(privPath)
+	assert.NoError(t, err)
+	publicKey, err := LoadVerificationKeyFile(pubPath)
+	assert.NoError(t, err)
+	assert.Equal(t, edKey.Public(), publicKey)
+
+	// The thumbprint kid is the same whether derived from the private or public key
+	ks, err := NewKeySet(signer, "")
+	assert.NoError(t, err)
+	kid, err := thumbprint(publicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, kid, ks.JWKS().Keys[0].Kid)
+}
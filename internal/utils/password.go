@@ -1,17 +1,216 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
-// HashPassword hashes a plain-text password.
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrPasswordMismatch    = errors.New("password does not match")
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+	ErrInvalidPasswordHash = errors.New("malformed password hash")
+)
+
+// PasswordHasher hashes passwords into a self-describing string, so that
+// hashes produced with other algorithms or parameters can still be verified.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of the password.
+	Hash(password string) (string, error)
+	// Recognizes reports whether the encoded hash was produced by this algorithm.
+	Recognizes(encoded string) bool
+	// Verify returns ErrPasswordMismatch if the password doesn't match the encoded hash.
+	Verify(encoded, password string) error
+	// NeedsRehash reports whether the encoded hash uses other parameters than the hasher.
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idHasher hashes passwords with Argon2id (RFC 9106) and encodes them in
+// the PHC string format: $argon2id$v=19$m=<KiB>,t=<iterations>,p=<lanes>$<salt>$<hash>.
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// NewArgon2idHasher returns a hasher with the parameters recommended by OWASP.
+func NewArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{Memory: 19 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+// Verify recomputes the hash with the parameters stored in the encoded hash.
+func (h *Argon2idHasher) Verify(encoded, password string) error {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return err
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.Memory || params.Iterations != h.Iterations || params.Parallelism != h.Parallelism ||
+		uint32(len(salt)) < h.SaltLength || uint32(len(key)) != h.KeyLength
+}
+
+func decodeArgon2id(encoded string) (Argon2idHasher, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2idHasher{}, nil, nil, ErrInvalidPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idHasher{}, nil, nil, ErrInvalidPasswordHash
+	}
+	var params Argon2idHasher
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Argon2idHasher{}, nil, nil, ErrInvalidPasswordHash
+	}
+	if params.Iterations == 0 || params.Parallelism == 0 {
+		return Argon2idHasher{}, nil, nil, ErrInvalidPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idHasher{}, nil, nil, ErrInvalidPasswordHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2idHasher{}, nil, nil, ErrInvalidPasswordHash
+	}
+	return params, salt, key, nil
+}
+
+// BcryptHasher hashes passwords with bcrypt in its modular crypt format
+// ($2a$<cost>$...). bcrypt only uses the first 72 bytes of a password, so
+// longer passwords are rejected with bcrypt.ErrPasswordTooLong.
+type BcryptHasher struct {
+	Cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{Cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
 	if err != nil {
 		return "", err
 	}
 	return string(hashedPassword), nil
 }
 
+func (h *BcryptHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (h *BcryptHasher) Verify(encoded, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.Cost
+}
+
+// PasswordHashing hashes new passwords with the current hasher and verifies
+// stored hashes with whichever hasher produced them, so the algorithm or its
+// parameters can change without invalidating existing passwords.
+type PasswordHashing struct {
+	Current PasswordHasher
+	Legacy  []PasswordHasher // Only used to verify existing hashes
+}
+
+// NewPasswordHashing returns a PasswordHashing that can also verify the
+// hashes of the other supported algorithms.
+func NewPasswordHashing(current PasswordHasher) *PasswordHashing {
+	return &PasswordHashing{
+		Current: current,
+		Legacy:  []PasswordHasher{NewArgon2idHasher(), NewBcryptHasher(bcrypt.DefaultCost)},
+	}
+}
+
+func (p *PasswordHashing) Hash(password string) (string, error) {
+	return p.Current.Hash(password)
+}
+
+func (p *PasswordHashing) Verify(encoded, password string) error {
+	hasher, err := p.hasherFor(encoded)
+	if err != nil {
+		return err
+	}
+	return hasher.Verify(encoded, password)
+}
+
+// NeedsRehash reports whether the encoded hash was produced with another
+// algorithm or other parameters than the current hasher.
+func (p *PasswordHashing) NeedsRehash(encoded string) bool {
+	return !p.Current.Recognizes(encoded) || p.Current.NeedsRehash(encoded)
+}
+
+func (p *PasswordHashing) hasherFor(encoded string) (PasswordHasher, error) {
+	if p.Current.Recognizes(encoded) {
+		return p.Current, nil
+	}
+	for _, hasher := range p.Legacy {
+		if hasher.Recognizes(encoded) {
+			return hasher, nil
+		}
+	}
+	return nil, ErrUnknownPasswordHash
+}
+
+// Passwords is the password hashing configuration used by HashPassword and
+// VerifyPassword. It is set once at startup.
+var Passwords = NewPasswordHashing(NewArgon2idHasher())
+
+// HashPassword hashes a plain-text password.
+func HashPassword(password string) (string, error) {
+	return Passwords.Hash(password)
+}
+
 // VerifyPassword verifies a plain-text password against a hashed password.
 func VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return Passwords.Verify(hashedPassword, password)
+}
+
+// PasswordNeedsRehash reports whether a hashed password should be upgraded to
+// the current algorithm and parameters.
+func PasswordNeedsRehash(hashedPassword string) bool {
+	return Passwords.NeedsRehash(hashedPassword)
 }
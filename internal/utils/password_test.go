@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idHasher uses cheap parameters to keep the tests fast.
+func testArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func TestPasswordHashers(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher PasswordHasher
+		prefix string
+	}{
+		{"argon2id", testArgon2idHasher(), "$argon2id$v=19$m=64,t=1,p=1$"},
+		{"bcrypt", NewBcryptHasher(bcrypt.MinCost), "$2a$04$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.hasher.Hash("correct horse")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(encoded, tt.prefix), encoded)
+			assert.True(t, tt.hasher.Recognizes(encoded))
+			assert.False(t, tt.hasher.NeedsRehash(encoded))
+
+			assert.NoError(t, tt.hasher.Verify(encoded, "correct horse"))
+			assert.ErrorIs(t, tt.hasher.Verify(encoded, "wrong horse"), ErrPasswordMismatch)
+
+			// Salts are random
+			again, err := tt.hasher.Hash("correct horse")
+			assert.NoError(t, err)
+			assert.NotEqual(t, encoded, again)
+		})
+	}
+}
+
+func TestArgon2id_AcceptsLongPasswords(t *testing.T) {
+	hasher := testArgon2idHasher()
+	long := strings.Repeat("a", 100)
+
+	encoded, err := hasher.Hash(long)
+	assert.NoError(t, err)
+
+	// Unlike bcrypt, every byte counts
+	assert.NoError(t, hasher.Verify(encoded, long))
+	assert.ErrorIs(t, hasher.Verify(encoded, long[:72]), ErrPasswordMismatch)
+
+	_, err = NewBcryptHasher(bcrypt.MinCost).Hash(long)
+	assert.ErrorIs(t, err, bcrypt.ErrPasswordTooLong)
+}
+
+func TestArgon2id_RejectsMalformedHashes(t *testing.T) {
+	hasher := testArgon2idHasher()
+
+	for _, encoded := range []string{
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdA",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=64,t=1,p=1$!!!$aGFzaA",
+	} {
+		assert.ErrorIs(t, hasher.Verify(encoded, "password"), ErrInvalidPasswordHash, encoded)
+		assert.True(t, hasher.NeedsRehash(encoded), encoded)
+	}
+}
+
+func TestPasswordHashing_UpgradesOutdatedHashes(t *testing.T) {
+	current := testArgon2idHasher()
+	hashing := &PasswordHashing{Current: current, Legacy: []PasswordHasher{NewBcryptHasher(bcrypt.MinCost)}}
+
+	// A bcrypt hash from before the switch still verifies, but needs a rehash
+	legacy, err := NewBcryptHasher(bcrypt.MinCost).Hash("password")
+	assert.NoError(t, err)
+	assert.NoError(t, hashing.Verify(legacy, "password"))
+	assert.True(t, hashing.NeedsRehash(legacy))
+
+	// So does an Argon2id hash with weaker parameters
+	weaker := *current
+	weaker.Memory = 32
+	outdated, err := weaker.Hash("password")
+	assert.NoError(t, err)
+	assert.NoError(t, hashing.Verify(outdated, "password"))
+	assert.True(t, hashing.NeedsRehash(outdated))
+
+	upgraded, err := hashing.Hash("password")
+	assert.NoError(t, err)
+	assert.False(t, hashing.NeedsRehash(upgraded))
+
+	assert.ErrorIs(t, hashing.Verify("plaintext", "plaintext"), ErrUnknownPasswordHash)
+}
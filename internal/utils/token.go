@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrInvalidTokenClaims = errors.New("invalid token claims")
+)
+
+// Claims are the claims of every JWT issued by TokenService. The user ID is
+// carried in the registered sub claim. Tokens issued to OAuth clients also
+// carry the client ID and the granted scope, a space-separated list.
+// Impersonation tokens name the staff member acting as the user in act, and
+// tokens issued on login carry the ID of the login session in sid.
+type Claims struct {
+	Role      string       `json:"role,omitempty"`
+	Type      string       `json:"typ"`
+	ClientID  string       `json:"client_id,omitempty"`
+	Scope     string       `json:"scope,omitempty"`
+	Actor     *ActorClaims `json:"act,omitempty"`
+	SessionID int          `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ActorClaims identify the party acting on behalf of the subject (RFC 8693
+// section 4.1).
+type ActorClaims struct {
+	Subject string `json:"sub"`
+}
+
+// ActorID returns the user ID of the actor of an impersonation token. ok is
+// false for tokens used by the subject themselves.
+func (c *Claims) ActorID() (int, bool, error) {
+	if c.Actor == nil {
+		return 0, false, nil
+	}
+	actorID, err := strconv.Atoi(c.Actor.Subject)
+	if err != nil || actorID <= 0 {
+		return 0, false, ErrInvalidTokenClaims
+	}
+	return actorID, true, nil
+}
+
+// UserID returns the user ID from the sub claim.
+func (c *Claims) UserID() (int, error) {
+	userID, err := strconv.Atoi(c.Subject)
+	if err != nil || userID <= 0 {
+		return 0, ErrInvalidTokenClaims
+	}
+	return userID, nil
+}
+
+// TokenService issues and verifies JWTs. Tokens are signed with Keys and must
+// match Issuer and Audience when verified; ClockSkew is tolerated on the
+// exp, nbf and iat claims.
+type TokenService struct {
+	Keys             *KeySet
+	Issuer           string
+	Audience         string
+	AccessTokenTTL   time.Duration
+	MFAChallengeTTL  time.Duration
+	ImpersonationTTL time.Duration
+	ClockSkew        time.Duration
+}
+
+func NewTokenService(keys *KeySet, issuer, audience string) *TokenService {
+	return &TokenService{
+		Keys:             keys,
+		Issuer:           issuer,
+		Audience:         audience,
+		AccessTokenTTL:   AccessTokenTTL,
+		MFAChallengeTTL:  MFAChallengeTTL,
+		ImpersonationTTL: ImpersonationTTL,
+		ClockSkew:        DefaultClockSkew,
+	}
+}
+
+// IssueAccessToken issues a short-lived access token for the user's login
+// session. Every token carries a unique jti so it can be revoked individually,
+// and the session ID so it is revoked along with the session.
+func (s *TokenService) IssueAccessToken(userID int, role string, sessionID int) (string, error) {
+	return s.issue(Claims{Role: role, Type: TokenTypeAccess, SessionID: sessionID}, userID, s.AccessTokenTTL)
+}
+
+// IssueOAuthAccessToken issues an access token to an OAuth client on behalf of
+// the user, limited to the given scope.
+func (s *TokenService) IssueOAuthAccessToken(userID int, role, clientID, scope string) (string, error) {
+	return s.issue(Claims{Role: role, Type: TokenTypeAccess, ClientID: clientID, Scope: scope}, userID, s.AccessTokenTTL)
+}
+
+// IssueImpersonationToken issues an access token for the user that records
+// the actor impersonating them in the act claim. It returns the jti and the
+// expiry along with the token so the session can be audited.
+func (s *TokenService) IssueImpersonationToken(userID int, role string, actorID int) (string, string, time.Time, error) {
+	claims := Claims{Role: role, Type: TokenTypeAccess, Actor: &ActorClaims{Subject: strconv.Itoa(actorID)}}
+	token, err := s.sign(&claims, userID, s.ImpersonationTTL)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	return token, claims.ID, claims.ExpiresAt.Time, nil
+}
+
+// IssueMFAChallengeToken issues a short-lived token proving that the user
+// passed the password step of a login that still requires a second factor.
+func (s *TokenService) IssueMFAChallengeToken(userID int) (string, error) {
+	return s.issue(Claims{Type: TokenTypeMFAChallenge}, userID, s.MFAChallengeTTL)
+}
+
+// ParseAccessToken verifies an access token and returns its claims.
+func (s *TokenService) ParseAccessToken(tokenString string) (*Claims, error) {
+	claims, err := s.parse(tokenString, TokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, ErrInvalidTokenClaims
+	}
+	return claims, nil
+}
+
+// ParseMFAChallengeToken verifies a token from IssueMFAChallengeToken and
+// returns its claims. The jti lets callers redeem the challenge only once.
+func (s *TokenService) ParseMFAChallengeToken(tokenString string) (*Claims, error) {
+	claims, err := s.parse(tokenString, TokenTypeMFAChallenge)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, ErrInvalidTokenClaims
+	}
+	if _, err := claims.UserID(); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// issue fills in the registered claims and signs the token.
+func (s *TokenService) issue(claims Claims, userID int, ttl time.Duration) (string, error) {
+	return s.sign(&claims, userID, ttl)
+}
+
+// sign fills in the registered claims in place and signs the token.
+func (s *TokenService) sign(claims *Claims, userID int, ttl time.Duration) (string, error) {
+	jti, err := generateTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    s.Issuer,
+		Subject:   strconv.Itoa(userID),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		NotBefore: jwt.NewNumericDate(now),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ID:        jti,
+	}
+	if s.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{s.Audience}
+	}
+	return s.Keys.Sign(claims)
+}
+
+// parse verifies the signature and the registered claims, and checks that the
+// token was issued for the given purpose.
+func (s *TokenService) parse(tokenString, typ string) (*Claims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithLeeway(s.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if s.Issuer != "" {
+		options = append(options, jwt.WithIssuer(s.Issuer))
+	}
+	if s.Audience != "" {
+		options = append(options, jwt.WithAudience(s.Audience))
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.Keys.Keyfunc, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.Type != typ || claims.IssuedAt == nil {
+		return nil, ErrInvalidTokenClaims
+	}
+	if _, err := claims.UserID(); err != nil {
+		return nil, err
+	}
+	if _, _, err := claims.ActorID(); err != nil {
+		return nil, err
+	}
+	if claims.SessionID < 0 {
+		return nil, ErrInvalidTokenClaims
+	}
+	return claims, nil
+}
@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTokenService() *TokenService {
+	return NewTokenService(NewHMACKeySet([]byte("test-secret")), "test-issuer", "test-audience")
+}
+
+// validClaims returns access token claims that TokenService accepts.
+func validClaims() Claims {
+	now := time.Now()
+	return Claims{
+		Role: "user",
+		Type: TokenTypeAccess,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "test-issuer",
+			Subject:   "1",
+			Audience:  jwt.ClaimStrings{"test-audience"},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        "jti",
+		},
+	}
+}
+
+func TestTokenService_IssueAndParseAccessToken(t *testing.T) {
+	service := newTestTokenService()
+
+	token, err := service.IssueAccessToken(42, "admin", 5)
+	assert.NoError(t, err)
+
+	claims, err := service.ParseAccessToken(token)
+	assert.NoError(t, err)
+	userID, err := claims.UserID()
+	assert.NoError(t, err)
+	assert.Equal(t, 42, userID)
+	assert.Equal(t, "admin", claims.Role)
+	assert.Equal(t, 5, claims.SessionID)
+	assert.Equal(t, "test-issuer", claims.Issuer)
+	assert.Equal(t, jwt.ClaimStrings{"test-audience"}, claims.Audience)
+	assert.NotEmpty(t, claims.ID)
+	assert.NotNil(t, claims.NotBefore)
+}
+
+func TestTokenService_ParseAccessToken(t *testing.T) {
+	service := newTestTokenService()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	rsaKeys, err := NewKeySet(rsaKey, "")
+	assert.NoError(t, err)
+
+	sign := func(claims Claims) string {
+		token, err := service.Keys.Sign(claims)
+		assert.NoError(t, err)
+		return token
+	}
+
+	tests := []struct {
+		name  string
+		token func() string
+		err   error
+	}{
+		{
+			name:  "valid",
+			token: func() string { return sign(validClaims()) },
+		},
+		{
+			name: "expired",
+			token: func() string {
+				claims := validClaims()
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return sign(claims)
+			},
+			err: jwt.ErrTokenExpired,
+		},
+		{
+			name: "expired within clock skew",
+			token: func() string {
+				claims := validClaims()
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-service.ClockSkew / 2))
+				return sign(claims)
+			},
+		},
+		{
+			name: "missing exp",
+			token: func() string {
+				claims := validClaims()
+				claims.ExpiresAt = nil
+				return sign(claims)
+			},
+			err: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name: "not yet valid",
+			token: func() string {
+				claims := validClaims()
+				claims.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+				return sign(claims)
+			},
+			err: jwt.ErrTokenNotValidYet,
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				claims := validClaims()
+				claims.Audience = jwt.ClaimStrings{"other-service"}
+				return sign(claims)
+			},
+			err: jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				claims := validClaims()
+				claims.Issuer = "someone-else"
+				return sign(claims)
+			},
+			err: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name: "wrong alg",
+			token: func() string {
+				token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims()).SignedString(rsaKey)
+				assert.NoError(t, err)
+				return token
+			},
+			err: ErrUnexpectedAlg,
+		},
+		{
+			name: "unknown kid",
+			token: func() string {
+				token, err := rsaKeys.Sign(validClaims())
+				assert.NoError(t, err)
+				return token
+			},
+			err: ErrUnknownKeyID,
+		},
+		{
+			name: "alg none",
+			token: func() string {
+				token, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				assert.NoError(t, err)
+				return token
+			},
+			err: ErrUnexpectedAlg,
+		},
+		{
+			name: "wrong signature",
+			token: func() string {
+				token, err := NewHMACKeySet([]byte("other-secret")).Sign(validClaims())
+				assert.NoError(t, err)
+				return token
+			},
+			err: jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name: "MFA challenge token",
+			token: func() string {
+				claims := validClaims()
+				claims.Type = TokenTypeMFAChallenge
+				return sign(claims)
+			},
+			err: ErrInvalidTokenClaims,
+		},
+		{
+			name: "missing jti",
+			token: func() string {
+				claims := validClaims()
+				claims.ID = ""
+				return sign(claims)
+			},
+			err: ErrInvalidTokenClaims,
+		},
+		{
+			name: "invalid subject",
+			token: func() string {
+				claims := validClaims()
+				claims.Subject = "john"
+				return sign(claims)
+			},
+			err: ErrInvalidTokenClaims,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.ParseAccessToken(tt.token())
+			if tt.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestTokenService_MFAChallengeToken(t *testing.T) {
+	service := newTestTokenService()
+
+	challenge, err := service.IssueMFAChallengeToken(7)
+	assert.NoError(t, err)
+
+	claims, err := service.ParseMFAChallengeToken(challenge)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, claims.ID)
+	userID, err := claims.UserID()
+	assert.NoError(t, err)
+	assert.Equal(t, 7, userID)
+
+	// A challenge token can't be used as an access token and vice versa
+	_, err = service.ParseAccessToken(challenge)
+	assert.ErrorIs(t, err, ErrInvalidTokenClaims)
+
+	access, err := service.IssueAccessToken(7, "user", 0)
+	assert.NoError(t, err)
+	_, err = service.ParseMFAChallengeToken(access)
+	assert.ErrorIs(t, err, ErrInvalidTokenClaims)
+}
+
+func TestTokenService_ImpersonationToken(t *testing.T) {
+	service := newTestTokenService()
+
+	token, jti, expiresAt, err := service.IssueImpersonationToken(42, "user", 3)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, jti)
+	assert.WithinDuration(t, time.Now().Add(ImpersonationTTL), expiresAt, 5*time.Second)
+
+	claims, err := service.ParseAccessToken(token)
+	assert.NoError(t, err)
+	userID, err := claims.UserID()
+	assert.NoError(t, err)
+	assert.Equal(t, 42, userID)
+	actorID, ok, err := claims.ActorID()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 3, actorID)
+
+	// Regular access tokens have no actor
+	access, err := service.IssueAccessToken(42, "user", 0)
+	assert.NoError(t, err)
+	claims, err = service.ParseAccessToken(access)
+	assert.NoError(t, err)
+	_, ok, err = claims.ActorID()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -3,25 +3,99 @@ package middleware
 import (
 	"context"
 	"errors"
-	"github.com/golang-jwt/jwt/v5"
+	"go-crud/internal/utils"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type contextKey string
 
 const (
-	userIDKey contextKey = "user_id" // Key to store user_id in the context
+	userIDKey      contextKey = "user_id"    // Key to store user_id in the context
+	roleKey        contextKey = "role"       // Key to store the user's role in the context
+	tokenIDKey     contextKey = "jti"        // Key to store the token's jti in the context
+	tokenExpiryKey contextKey = "expires_at" // Key to store the token's expiry in the context
+	scopesKey      contextKey = "scopes"     // Key to store the API key's scopes in the context
+	actorIDKey     contextKey = "actor_id"   // Key to store the impersonating staff member's user_id in the context
+	sessionIDKey   contextKey = "sid"        // Key to store the token's login session in the context
 )
 
 var (
 	ErrInvalidToken = errors.New("invalid or expired token")
 	ErrMissingAuth  = errors.New("missing Authorization header")
+	ErrRevokedToken = errors.New("token has been revoked")
+	ErrInvalidKey   = errors.New("invalid or expired API key")
 )
 
-// AuthMiddleware validates JWT tokens and ensures requests are authenticated
-func AuthMiddleware(secretKey []byte) func(next http.Handler) http.Handler {
+// Authorization header schemes accepted by AuthMiddleware.
+const (
+	schemeBearer = "Bearer "
+	schemeAPIKey = "ApiKey "
+)
+
+// RevocationChecker reports whether an otherwise valid token has been revoked.
+// sessionID is 0 for tokens not bound to a login session.
+type RevocationChecker interface {
+	IsRevoked(jti string, userID, sessionID int, issuedAt time.Time) (bool, error)
+}
+
+// APIKeyAuthenticator resolves an API key to its owner, the owner's role and
+// the key's scopes. ok is false for unknown or expired keys.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(key string) (userID int, role string, scopes []string, ok bool, err error)
+}
+
+// UserIDFromContext returns the ID of the authenticated user.
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(userIDKey).(int)
+	return userID, ok
+}
+
+// RoleFromContext returns the role of the authenticated user.
+func RoleFromContext(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(roleKey).(string)
+	return role, ok
+}
+
+// TokenIDFromContext returns the jti and expiry of the token that authenticated the request.
+func TokenIDFromContext(ctx context.Context) (string, time.Time, bool) {
+	jti, ok := ctx.Value(tokenIDKey).(string)
+	if !ok {
+		return "", time.Time{}, false
+	}
+	expiresAt, _ := ctx.Value(tokenExpiryKey).(time.Time)
+	return jti, expiresAt, true
+}
+
+// ActorIDFromContext returns the ID of the staff member impersonating the
+// authenticated user. ok is false unless the request uses an impersonation token.
+func ActorIDFromContext(ctx context.Context) (int, bool) {
+	actorID, ok := ctx.Value(actorIDKey).(int)
+	return actorID, ok
+}
+
+// SessionIDFromContext returns the login session of the token that
+// authenticated the request. ok is false for tokens not bound to a session.
+func SessionIDFromContext(ctx context.Context) (int, bool) {
+	sessionID, ok := ctx.Value(sessionIDKey).(int)
+	return sessionID, ok
+}
+
+// ScopesFromContext returns the scopes of the API key or OAuth access token
+// that authenticated the request. ok is false for first-party tokens, which
+// are not limited by scopes.
+func ScopesFromContext(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(scopesKey).([]string)
+	return scopes, ok
+}
+
+// AuthMiddleware validates JWT tokens and ensures requests are authenticated.
+// The token service checks signatures and registered claims, and tokens
+// reported as revoked by revocations are rejected. When apiKeys is not nil,
+// requests may instead authenticate with an "ApiKey <key>" header.
+func AuthMiddleware(tokens *utils.TokenService, revocations RevocationChecker, apiKeys APIKeyAuthenticator) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			log.Println("Middleware: Starting token validation...")
@@ -32,49 +106,103 @@ func AuthMiddleware(secretKey []byte) func(next http.Handler) http.Handler {
 				http.Error(w, ErrMissingAuth.Error(), http.StatusUnauthorized)
 				return
 			}
+			// API keys are checked separately and carry no jti to revoke
+			if key, found := strings.CutPrefix(authHeader, schemeAPIKey); found && apiKeys != nil {
+				authenticateAPIKey(w, r, next, apiKeys, key)
+				return
+			}
 			// Step 2: Validate the header format (Bearer <token>)
-			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+			tokenString := strings.TrimPrefix(authHeader, schemeBearer)
 			if tokenString == authHeader { // No "Bearer " prefix found
 				log.Println("Middleware: Invalid Authorization header format")
 				http.Error(w, "invalid Authorization header format", http.StatusUnauthorized)
 				return
 			}
-			// Step 3: Parse and verify the token
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				// Ensure the signing method is HMAC
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					log.Println("Middleware: Invalid signing method")
-					return nil, ErrInvalidToken
-				}
-				return secretKey, nil
-			})
-
-			if err != nil || !token.Valid {
+			// Step 3: Verify the signature, registered claims and token type
+			claims, err := tokens.ParseAccessToken(tokenString)
+			if err != nil {
 				log.Printf("Middleware: Token validation failed: %v", err)
 				http.Error(w, ErrInvalidToken.Error(), http.StatusUnauthorized)
 				return
 			}
-			// Step 4: Extract claims and validate them
-			claims, ok := token.Claims.(jwt.MapClaims)
-			if !ok {
-				log.Println("Middleware: Invalid token claims")
-				http.Error(w, ErrInvalidToken.Error(), http.StatusUnauthorized)
+			// Step 4: Extract the user from the sub claim
+			userID, err := claims.UserID()
+			if err != nil {
+				log.Println("Middleware: Missing or invalid sub claim")
+				http.Error(w, "invalid token claims", http.StatusUnauthorized)
 				return
 			}
-
-			// Extract user_id from claims
-			userID, ok := claims["user_id"].(float64) // JWT stores numbers as float64
-			if !ok {
-				log.Println("Middleware: Missing or invalid user_id claim")
+			actorID, impersonated, err := claims.ActorID()
+			if err != nil {
+				log.Println("Middleware: Invalid act claim")
 				http.Error(w, "invalid token claims", http.StatusUnauthorized)
 				return
 			}
+			// Tokens without a role claim get no elevated privileges
+			role := claims.Role
+			jti := claims.ID
+
+			// Step 5: Reject revoked tokens
+			revoked, err := revocations.IsRevoked(jti, userID, claims.SessionID, claims.IssuedAt.Time)
+			if err != nil {
+				log.Printf("Middleware: Revocation check failed: %v", err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				log.Printf("Middleware: Token %s has been revoked", jti)
+				http.Error(w, ErrRevokedToken.Error(), http.StatusUnauthorized)
+				return
+			}
 
-			log.Printf("Middleware: Token validated successfully for user_id: %d", int(userID))
+			if impersonated {
+				log.Printf("Middleware: Token validated successfully for user_id: %d, impersonated by user_id: %d", userID, actorID)
+			} else {
+				log.Printf("Middleware: Token validated successfully for user_id: %d", userID)
+			}
 
-			// Step 5: Add user_id to the request context
-			ctx := context.WithValue(r.Context(), userIDKey, int(userID))
+			// Step 6: Add user_id, role, token identity and the actor to the request context
+			ctx := context.WithValue(r.Context(), userIDKey, userID)
+			ctx = context.WithValue(ctx, roleKey, role)
+			ctx = context.WithValue(ctx, tokenIDKey, jti)
+			ctx = context.WithValue(ctx, tokenExpiryKey, claims.ExpiresAt.Time)
+			// Tokens issued to OAuth clients are limited to their granted scope
+			if claims.ClientID != "" {
+				ctx = context.WithValue(ctx, scopesKey, strings.Fields(claims.Scope))
+			}
+			if impersonated {
+				ctx = context.WithValue(ctx, actorIDKey, actorID)
+			}
+			if claims.SessionID != 0 {
+				ctx = context.WithValue(ctx, sessionIDKey, claims.SessionID)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// authenticateAPIKey authenticates the request with an API key and adds the
+// owner, role and key scopes to the request context.
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, apiKeys APIKeyAuthenticator, key string) {
+	userID, role, scopes, ok, err := apiKeys.AuthenticateAPIKey(key)
+	if err != nil {
+		log.Printf("Middleware: API key check failed: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		log.Println("Middleware: Invalid API key")
+		http.Error(w, ErrInvalidKey.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	log.Printf("Middleware: API key validated successfully for user_id: %d", userID)
+
+	if scopes == nil {
+		scopes = []string{}
+	}
+	ctx := context.WithValue(r.Context(), userIDKey, userID)
+	ctx = context.WithValue(ctx, roleKey, role)
+	ctx = context.WithValue(ctx, scopesKey, scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// RequireRole allows the request only if the authenticated user has one of
+// the given roles. It must run after AuthMiddleware.
+func RequireRole(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasRole(r, roles) {
+				log.Println("Middleware: Insufficient role")
+				http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSelfOrRole allows the request if the route variable named param
+// matches the authenticated user's ID, or if the user has one of the given
+// roles. It must run after AuthMiddleware.
+func RequireSelfOrRole(param string, roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hasRole(r, roles) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, ok := UserIDFromContext(r.Context())
+			targetID, err := strconv.Atoi(mux.Vars(r)[param])
+			if !ok || err != nil || userID != targetID {
+				log.Println("Middleware: Request is neither from the owner nor a privileged role")
+				http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope allows requests authenticated with an API key or OAuth access
+// token only if it has the given scope. First-party tokens are not limited by
+// scopes. It must run after AuthMiddleware.
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, ok := ScopesFromContext(r.Context())
+			if ok && !slices.Contains(scopes, scope) {
+				log.Printf("Middleware: API key lacks scope %s", scope)
+				http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DenyScopedCredentials rejects requests authenticated with an API key or an
+// OAuth access token, for routes that manage the account or its credentials.
+// It must run after AuthMiddleware.
+func DenyScopedCredentials(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ScopesFromContext(r.Context()); ok {
+			log.Println("Middleware: Scoped credentials are not accepted for this route")
+			http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DenyImpersonation rejects requests made with an impersonation token, for
+// sensitive operations such as changing the password or deleting the account
+// that support staff must never perform on a user's behalf. It must run after
+// AuthMiddleware.
+func DenyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if actorID, ok := ActorIDFromContext(r.Context()); ok {
+			log.Printf("Middleware: Sensitive operation denied while user_id %d is impersonating", actorID)
+			http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func hasRole(r *http.Request, roles []string) bool {
+	role, ok := RoleFromContext(r.Context())
+	if !ok {
+		return false
+	}
+	for _, allowed := range roles {
+		if role == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,8 @@
+-- Keyset pagination orders by the sort field with the ID as a tie-breaker
+CREATE INDEX IF NOT EXISTS idx_users_name_id ON users (name, id);
+CREATE INDEX IF NOT EXISTS idx_users_email_id ON users (email, id);
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at, id);
+
+-- Filters on name prefix and email domain
+CREATE INDEX IF NOT EXISTS idx_users_lower_name ON users (LOWER(name) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_email_domain ON users (LOWER(SPLIT_PART(email, '@', 2)));
@@ -0,0 +1,3 @@
+-- Incremented on every change, for optimistic concurrency control with ETags
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
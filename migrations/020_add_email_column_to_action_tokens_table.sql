@@ -0,0 +1,4 @@
+-- The address a token was sent to; verification tokens are only valid while
+-- the account still has that address
+ALTER TABLE action_tokens
+ADD COLUMN IF NOT EXISTS email VARCHAR(255);
@@ -0,0 +1,14 @@
+-- Whether the authorization request included redirect_uri, which the token
+-- request must then repeat (RFC 6749 section 4.1.3)
+ALTER TABLE oauth_authorization_codes
+ADD COLUMN IF NOT EXISTS redirect_uri_provided BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- Scopes each user approved for each client on the consent screen
+CREATE TABLE IF NOT EXISTS oauth_consents (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+    scope TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, client_id)
+);